@@ -0,0 +1,506 @@
+package cftest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/internal/sqlsplit"
+	_ "modernc.org/sqlite"
+)
+
+// d1DefaultPerPage is the page size of the list databases endpoint
+const d1DefaultPerPage = 1000
+
+type d1Database struct {
+	uuid      string
+	name      string
+	createdAt time.Time
+	db        *sql.DB
+}
+
+// d1Info is the JSON representation of a database
+type d1Info struct {
+	UUID      string    `json:"uuid"`
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	NumTables int       `json:"num_tables"`
+	FileSize  int64     `json:"file_size"`
+}
+
+func (d *d1Database) info(ctx context.Context) d1Info {
+	info := d1Info{
+		UUID:      d.uuid,
+		Name:      d.name,
+		Version:   "production",
+		CreatedAt: d.createdAt,
+	}
+
+	d.db.QueryRowContext(ctx,
+		"SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&info.NumTables)
+
+	var pageCount, pageSize int64
+	d.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount)
+	d.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize)
+	info.FileSize = pageCount * pageSize
+
+	return info
+}
+
+func (s *Server) routeD1(mux *http.ServeMux) {
+	const prefix = "/accounts/{account}/d1/database"
+
+	mux.HandleFunc("GET "+prefix, s.account(s.d1List))
+	mux.HandleFunc("POST "+prefix, s.account(s.d1Create))
+	mux.HandleFunc("GET "+prefix+"/{db}", s.account(s.d1Get))
+	mux.HandleFunc("DELETE "+prefix+"/{db}", s.account(s.d1Delete))
+	mux.HandleFunc("POST "+prefix+"/{db}/query", s.account(s.d1Query))
+	mux.HandleFunc("POST "+prefix+"/{db}/raw", s.account(s.d1Raw))
+
+	notImplemented := func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotImplemented, codeNotImplemented, "not implemented by cftest")
+	}
+	mux.HandleFunc(prefix+"/{db}/export", notImplemented)
+	mux.HandleFunc(prefix+"/{db}/import", notImplemented)
+	mux.HandleFunc(prefix+"/{db}/time_travel/", notImplemented)
+}
+
+// CreateD1Database creates an empty D1 database and returns its ID
+func (s *Server) CreateD1Database(name string) string {
+	db, err := s.createD1Database(name)
+	if err != nil {
+		panic(fmt.Sprintf("cftest: %v", err))
+	}
+	return db.uuid
+}
+
+func (s *Server) createD1Database(name string) (*d1Database, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, db := range s.d1 {
+		if db.name == name {
+			return nil, fmt.Errorf("database with name '%s' already exists", name)
+		}
+	}
+
+	conn, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database: %w", err)
+	}
+	// Every connection to :memory: is a separate database, so keep exactly one
+	conn.SetMaxOpenConns(1)
+	conn.SetConnMaxLifetime(0)
+	conn.SetConnMaxIdleTime(0)
+
+	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error configuring sqlite database: %w", err)
+	}
+
+	db := &d1Database{
+		uuid:      s.newID(),
+		name:      name,
+		createdAt: s.now().UTC(),
+		db:        conn,
+	}
+	s.d1[db.uuid] = db
+
+	return db, nil
+}
+
+// d1Database returns the database named in the request path, writing an error if there is none
+func (s *Server) d1Database(w http.ResponseWriter, r *http.Request) (*d1Database, bool) {
+	s.mu.Lock()
+	db, ok := s.d1[r.PathValue("db")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, codeD1NotFound, "The database "+r.PathValue("db")+" could not be found")
+	}
+	return db, ok
+}
+
+func (s *Server) d1List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("name")
+
+	page, perPage := 1, d1DefaultPerPage
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid page")
+			return
+		}
+		page = n
+	}
+	if v := query.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > d1DefaultPerPage {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid per_page")
+			return
+		}
+		perPage = n
+	}
+
+	s.mu.Lock()
+	var databases []*d1Database
+	for _, db := range s.d1 {
+		if strings.Contains(db.name, name) {
+			databases = append(databases, db)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(databases, func(i, j int) bool { return databases[i].uuid < databases[j].uuid })
+
+	total := len(databases)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	infos := []d1Info{}
+	for _, db := range databases[start:end] {
+		infos = append(infos, db.info(r.Context()))
+	}
+
+	writeEnvelope(w, http.StatusOK, envelope{
+		Success: true,
+		Result:  infos,
+		ResultInfo: map[string]interface{}{
+			"page":        page,
+			"per_page":    perPage,
+			"count":       len(infos),
+			"total_count": total,
+		},
+	})
+}
+
+func (s *Server) d1Create(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "a database name is required")
+		return
+	}
+
+	db, err := s.createD1Database(body.Name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	writeResult(w, db.info(r.Context()))
+}
+
+func (s *Server) d1Get(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.d1Database(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, db.info(r.Context()))
+}
+
+func (s *Server) d1Delete(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.d1Database(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.d1, db.uuid)
+	s.mu.Unlock()
+
+	db.db.Close()
+	writeResult(w, nil)
+}
+
+// d1Statement is a statement in a query or raw request
+type d1Statement struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params"`
+}
+
+// d1Meta is the meta object of a statement result
+type d1Meta struct {
+	ChangedDB       bool    `json:"changed_db"`
+	Changes         int64   `json:"changes"`
+	Duration        float64 `json:"duration"`
+	LastRowID       int64   `json:"last_row_id"`
+	RowsRead        int     `json:"rows_read"`
+	RowsWritten     int64   `json:"rows_written"`
+	ServedByPrimary bool    `json:"served_by_primary"`
+	ServedByRegion  string  `json:"served_by_region"`
+	SizeAfter       int64   `json:"size_after"`
+}
+
+// d1Result is the result of one statement, with rows as arrays in column order
+type d1Result struct {
+	columns []string
+	rows    [][]interface{}
+	meta    d1Meta
+}
+
+func (s *Server) d1Query(w http.ResponseWriter, r *http.Request) {
+	s.d1Execute(w, r, func(result d1Result) interface{} {
+		rows := make([]map[string]interface{}, len(result.rows))
+		for i, row := range result.rows {
+			rows[i] = make(map[string]interface{}, len(row))
+			for j, value := range row {
+				rows[i][result.columns[j]] = value
+			}
+		}
+		return map[string]interface{}{
+			"meta":    result.meta,
+			"results": rows,
+			"success": true,
+		}
+	})
+}
+
+func (s *Server) d1Raw(w http.ResponseWriter, r *http.Request) {
+	s.d1Execute(w, r, func(result d1Result) interface{} {
+		return map[string]interface{}{
+			"meta": result.meta,
+			"results": map[string]interface{}{
+				"columns": result.columns,
+				"rows":    result.rows,
+			},
+			"success": true,
+		}
+	})
+}
+
+// d1Execute decodes a single statement or a batch, runs it in a transaction
+// and writes one result per SQL statement using format
+func (s *Server) d1Execute(w http.ResponseWriter, r *http.Request, format func(d1Result) interface{}) {
+	db, ok := s.d1Database(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		d1Statement
+		Batch []d1Statement `json:"batch"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	// Every SQL statement has its own result, including each statement of a
+	// batch entry that contains several
+	statements := body.Batch
+	if statements == nil {
+		statements = []d1Statement{body.d1Statement}
+	}
+
+	results, err := db.execute(r.Context(), statements)
+	if err != nil {
+		// Like D1, report only the error; the transaction was rolled back
+		writeError(w, http.StatusBadRequest, codeD1QueryError, err.Error())
+		return
+	}
+
+	formatted := make([]interface{}, len(results))
+	for i, result := range results {
+		formatted[i] = format(result)
+	}
+	writeResult(w, formatted)
+}
+
+// execute runs statements in a single transaction, which is rolled back if any fails
+func (d *d1Database) execute(ctx context.Context, statements []d1Statement) ([]d1Result, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var results []d1Result
+	for _, stmt := range statements {
+		args, err := bindParams(stmt.Params)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := sqlsplit.Split(stmt.SQL)
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("No SQL statements detected.")
+		}
+		if len(parts) > 1 && len(args) > 0 {
+			return nil, fmt.Errorf("parameters are not supported with multiple statements")
+		}
+
+		for _, part := range parts {
+			result, err := executeStatement(ctx, tx, part, args)
+			if err != nil {
+				return nil, fmt.Errorf("%v: SQLITE_ERROR", err)
+			}
+			results = append(results, result)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	var pageCount, pageSize int64
+	d.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount)
+	d.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize)
+	for i := range results {
+		results[i].meta.SizeAfter = pageCount * pageSize
+	}
+
+	return results, nil
+}
+
+// executeStatement runs a single SQL statement and collects its rows and meta
+func executeStatement(ctx context.Context, tx *sql.Tx, query string, args []interface{}) (d1Result, error) {
+	start := time.Now()
+
+	var before int64
+	if err := tx.QueryRowContext(ctx, "SELECT total_changes()").Scan(&before); err != nil {
+		return d1Result{}, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return d1Result{}, err
+	}
+
+	result, err := scanRows(rows)
+	if err != nil {
+		return d1Result{}, err
+	}
+
+	var after, lastRowID int64
+	if err := tx.QueryRowContext(ctx, "SELECT total_changes(), last_insert_rowid()").Scan(&after, &lastRowID); err != nil {
+		return d1Result{}, err
+	}
+
+	result.meta.Changes = after - before
+	result.meta.RowsWritten = result.meta.Changes
+	result.meta.ChangedDB = result.meta.Changes > 0 || !isQuery(query)
+	result.meta.LastRowID = lastRowID
+	result.meta.RowsRead = len(result.rows)
+	result.meta.ServedByPrimary = true
+	result.meta.ServedByRegion = "cftest"
+	result.meta.Duration = float64(time.Since(start).Microseconds()) / 1000
+
+	return result, nil
+}
+
+// scanRows reads every row, converting values to their D1 JSON representation
+func scanRows(rows *sql.Rows) (d1Result, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return d1Result{}, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return d1Result{}, err
+	}
+
+	result := d1Result{columns: columns, rows: [][]interface{}{}}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return d1Result{}, err
+		}
+
+		for i, value := range values {
+			values[i] = jsonValue(value, types[i].DatabaseTypeName())
+		}
+		result.rows = append(result.rows, values)
+	}
+
+	return result, rows.Err()
+}
+
+// jsonValue converts a value read from SQLite to the form D1 returns
+func jsonValue(value interface{}, declType string) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		// D1 returns blobs as arrays of bytes
+		blob := make([]int, len(v))
+		for i, b := range v {
+			blob[i] = int(b)
+		}
+		return blob
+	case time.Time:
+		// The driver parses text in DATE and DATETIME columns, D1 returns it as stored
+		if strings.EqualFold(declType, "DATE") {
+			return v.Format("2006-01-02")
+		}
+		if v.Location() == time.UTC && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02 15:04:05")
+		}
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// bindParams converts JSON parameters to values SQLite can bind
+func bindParams(params []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		switch v := param.(type) {
+		case nil, string:
+			args[i] = v
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				args[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				args[i] = f
+			} else {
+				return nil, fmt.Errorf("invalid number parameter %s", v)
+			}
+		case bool:
+			args[i] = 0
+			if v {
+				args[i] = 1
+			}
+		case []interface{}:
+			blob := make([]byte, len(v))
+			for j, b := range v {
+				n, ok := b.(json.Number)
+				if !ok {
+					return nil, fmt.Errorf("unsupported parameter type at index %d", i)
+				}
+				value, err := n.Int64()
+				if err != nil || value < 0 || value > 255 {
+					return nil, fmt.Errorf("invalid blob parameter at index %d", i)
+				}
+				blob[j] = byte(value)
+			}
+			args[i] = blob
+		default:
+			return nil, fmt.Errorf("unsupported parameter type at index %d", i)
+		}
+	}
+	return args, nil
+}
+
+// isQuery reports whether a statement only reads data
+func isQuery(query string) bool {
+	keyword := strings.ToUpper(strings.TrimLeft(query, " \t\r\n("))
+	return strings.HasPrefix(keyword, "SELECT") || strings.HasPrefix(keyword, "EXPLAIN") ||
+		strings.HasPrefix(keyword, "WITH") || strings.HasPrefix(keyword, "VALUES")
+}
@@ -0,0 +1,527 @@
+package cftest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kvDefaultListLimit and kvMaxListLimit match the KV list keys endpoint
+const (
+	kvDefaultListLimit = 1000
+	kvMaxListLimit     = 1000
+)
+
+// kvMaxMetadataSize is the maximum size of a key's JSON metadata
+const kvMaxMetadataSize = 1024
+
+type kvEntry struct {
+	value      []byte
+	expiration int64
+	metadata   json.RawMessage
+}
+
+func (e *kvEntry) expired(now time.Time) bool {
+	return e.expiration != 0 && e.expiration <= now.Unix()
+}
+
+func (s *Server) routeKV(mux *http.ServeMux) {
+	const prefix = "/accounts/{account}/storage/kv/namespaces/{namespace}"
+
+	mux.HandleFunc("GET /accounts/{account}/storage/kv/namespaces", s.account(s.kvListNamespaces))
+	mux.HandleFunc("POST /accounts/{account}/storage/kv/namespaces", s.account(s.kvCreateNamespace))
+	mux.HandleFunc("PUT "+prefix, s.account(s.kvRenameNamespace))
+	mux.HandleFunc("DELETE "+prefix, s.account(s.kvDeleteNamespace))
+
+	mux.HandleFunc("GET "+prefix+"/keys", s.account(s.kvListKeys))
+	mux.HandleFunc("GET "+prefix+"/values/{key}", s.account(s.kvRead))
+	mux.HandleFunc("PUT "+prefix+"/values/{key}", s.account(s.kvWrite))
+	mux.HandleFunc("DELETE "+prefix+"/values/{key}", s.account(s.kvDelete))
+	mux.HandleFunc("GET "+prefix+"/metadata/{key}", s.account(s.kvMetadata))
+	mux.HandleFunc("PUT "+prefix+"/bulk", s.account(s.kvBulkWrite))
+	mux.HandleFunc("POST "+prefix+"/bulk/delete", s.account(s.kvBulkDelete))
+	mux.HandleFunc("POST "+prefix+"/bulk/get", s.account(s.kvBulkGet))
+}
+
+// KVValue returns the value stored under key in a KV namespace, for assertions
+func (s *Server) KVValue(namespaceID, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.kv[namespaceID][key]
+	if !ok || entry.expired(s.now()) {
+		return nil, false
+	}
+	return append([]byte(nil), entry.value...), true
+}
+
+// SetKVValue stores a value in a KV namespace without going through the API
+func (s *Server) SetKVValue(namespaceID, key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.kvNamespace(namespaceID)[key] = &kvEntry{value: append([]byte(nil), value...)}
+}
+
+// CreateKVNamespace creates an empty KV namespace and returns its ID
+func (s *Server) CreateKVNamespace(title string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.createKVNamespace(title)
+	if err != nil {
+		panic(fmt.Sprintf("cftest: %v", err))
+	}
+	return id
+}
+
+// createKVNamespace creates a namespace with a unique title. Callers hold s.mu.
+func (s *Server) createKVNamespace(title string) (string, error) {
+	for _, existing := range s.kvTitles {
+		if existing == title {
+			return "", fmt.Errorf("a namespace with this account ID and title already exists")
+		}
+	}
+
+	id := strings.ReplaceAll(s.newID(), "-", "")
+	s.kv[id] = make(map[string]*kvEntry)
+	s.kvTitles[id] = title
+	return id, nil
+}
+
+// kvNamespace returns a namespace, creating it on first use. Callers hold s.mu.
+func (s *Server) kvNamespace(namespaceID string) map[string]*kvEntry {
+	ns, ok := s.kv[namespaceID]
+	if !ok {
+		ns = make(map[string]*kvEntry)
+		s.kv[namespaceID] = ns
+		s.kvTitles[namespaceID] = namespaceID
+	}
+	return ns
+}
+
+type kvNamespaceInfo struct {
+	ID                  string `json:"id"`
+	Title               string `json:"title"`
+	SupportsURLEncoding bool   `json:"supports_url_encoding"`
+}
+
+func (s *Server) kvListNamespaces(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, perPage := 1, 20
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid page")
+			return
+		}
+		page = n
+	}
+	if v := query.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 5 || n > 1000 {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid per_page")
+			return
+		}
+		perPage = n
+	}
+
+	s.mu.Lock()
+	namespaces := make([]kvNamespaceInfo, 0, len(s.kvTitles))
+	for id, title := range s.kvTitles {
+		namespaces = append(namespaces, kvNamespaceInfo{ID: id, Title: title, SupportsURLEncoding: true})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Title < namespaces[j].Title })
+
+	total := len(namespaces)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	writeEnvelope(w, http.StatusOK, envelope{
+		Success: true,
+		Result:  namespaces[start:end],
+		ResultInfo: map[string]interface{}{
+			"page":        page,
+			"per_page":    perPage,
+			"count":       end - start,
+			"total_count": total,
+		},
+	})
+}
+
+func (s *Server) kvCreateNamespace(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Title == "" {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "a namespace title is required")
+		return
+	}
+
+	s.mu.Lock()
+	id, err := s.createKVNamespace(body.Title)
+	s.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeKVNamespaceExists, err.Error())
+		return
+	}
+
+	writeResult(w, kvNamespaceInfo{ID: id, Title: body.Title, SupportsURLEncoding: true})
+}
+
+func (s *Server) kvRenameNamespace(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Title == "" {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "a namespace title is required")
+		return
+	}
+
+	id := r.PathValue("namespace")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.kvTitles[id]; !ok {
+		writeError(w, http.StatusNotFound, codeKVNamespaceNotFound, "namespace not found")
+		return
+	}
+	for other, title := range s.kvTitles {
+		if title == body.Title && other != id {
+			writeError(w, http.StatusBadRequest, codeKVNamespaceExists, "a namespace with this account ID and title already exists")
+			return
+		}
+	}
+	s.kvTitles[id] = body.Title
+
+	writeResult(w, kvNamespaceInfo{ID: id, Title: body.Title, SupportsURLEncoding: true})
+}
+
+func (s *Server) kvDeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("namespace")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.kvTitles[id]; !ok {
+		writeError(w, http.StatusNotFound, codeKVNamespaceNotFound, "namespace not found")
+		return
+	}
+	delete(s.kv, id)
+	delete(s.kvTitles, id)
+
+	writeResult(w, nil)
+}
+
+type kvKey struct {
+	Name       string          `json:"name"`
+	Expiration int64           `json:"expiration,omitempty"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+}
+
+func (s *Server) kvListKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+
+	limit := kvDefaultListLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > kvMaxListLimit {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	after := ""
+	if cursor := query.Get("cursor"); cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "invalid cursor")
+			return
+		}
+		after = string(decoded)
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	var names []string
+	for name, entry := range s.kv[r.PathValue("namespace")] {
+		if strings.HasPrefix(name, prefix) && name > after && !entry.expired(now) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	cursor := ""
+	if len(names) > limit {
+		names = names[:limit]
+		cursor = base64.RawURLEncoding.EncodeToString([]byte(names[limit-1]))
+	}
+
+	keys := make([]kvKey, len(names))
+	for i, name := range names {
+		entry := s.kv[r.PathValue("namespace")][name]
+		keys[i] = kvKey{Name: name, Expiration: entry.expiration, Metadata: entry.metadata}
+	}
+	s.mu.Unlock()
+
+	writeEnvelope(w, http.StatusOK, envelope{
+		Success: true,
+		Result:  keys,
+		ResultInfo: map[string]interface{}{
+			"count":  len(keys),
+			"cursor": cursor,
+		},
+	})
+}
+
+func (s *Server) kvRead(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	entry, ok := s.kv[r.PathValue("namespace")][r.PathValue("key")]
+	if ok && entry.expired(s.now()) {
+		ok = false
+	}
+	var value []byte
+	if ok {
+		value = append([]byte(nil), entry.value...)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, codeKVKeyNotFound, "get: 'key not found'")
+		return
+	}
+
+	if entry.expiration != 0 {
+		w.Header().Set("Expiration", strconv.FormatInt(entry.expiration, 10))
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(value)
+}
+
+func (s *Server) kvMetadata(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	entry, ok := s.kv[r.PathValue("namespace")][r.PathValue("key")]
+	if ok && entry.expired(s.now()) {
+		ok = false
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, codeKVKeyNotFound, "metadata: 'key not found'")
+		return
+	}
+
+	writeResult(w, entry.metadata)
+}
+
+func (s *Server) kvWrite(w http.ResponseWriter, r *http.Request) {
+	entry := &kvEntry{}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "error parsing multipart form")
+			return
+		}
+		entry.value = []byte(r.FormValue("value"))
+
+		if metadata := r.FormValue("metadata"); metadata != "" {
+			if !json.Valid([]byte(metadata)) {
+				writeError(w, http.StatusBadRequest, codeBadRequest, "metadata must be valid JSON")
+				return
+			}
+			if len(metadata) > kvMaxMetadataSize {
+				writeError(w, http.StatusRequestEntityTooLarge, codeBadRequest, "metadata exceeds 1024 bytes")
+				return
+			}
+			entry.metadata = json.RawMessage(metadata)
+		}
+	} else {
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "error reading body")
+			return
+		}
+		entry.value = value
+	}
+
+	query := r.URL.Query()
+	if v := query.Get("expiration_ttl"); v != "" {
+		ttl, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ttl < 60 {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "Invalid expiration_ttl of "+v+". Expiration TTL must be at least 60.")
+			return
+		}
+		entry.expiration = s.now().Unix() + ttl
+	}
+	if v := query.Get("expiration"); v != "" {
+		at, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || at < s.now().Unix()+60 {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "Invalid expiration of "+v+". Expiration times must be at least 60 seconds in the future.")
+			return
+		}
+		entry.expiration = at
+	}
+
+	s.mu.Lock()
+	s.kvNamespace(r.PathValue("namespace"))[r.PathValue("key")] = entry
+	s.mu.Unlock()
+
+	writeResult(w, nil)
+}
+
+func (s *Server) kvDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	delete(s.kv[r.PathValue("namespace")], r.PathValue("key"))
+	s.mu.Unlock()
+
+	writeResult(w, nil)
+}
+
+// Limits of the bulk endpoints
+const (
+	kvMaxBulkWriteKeys  = 10000
+	kvMaxBulkDeleteKeys = 10000
+	kvMaxBulkGetKeys    = 100
+)
+
+type kvBulkResult struct {
+	SuccessfulKeyCount int      `json:"successful_key_count"`
+	UnsuccessfulKeys   []string `json:"unsuccessful_keys"`
+}
+
+func (s *Server) kvBulkWrite(w http.ResponseWriter, r *http.Request) {
+	var pairs []struct {
+		Key           string          `json:"key"`
+		Value         string          `json:"value"`
+		Base64        bool            `json:"base64"`
+		Expiration    int64           `json:"expiration"`
+		ExpirationTTL int64           `json:"expiration_ttl"`
+		Metadata      json.RawMessage `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&pairs); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(pairs) > kvMaxBulkWriteKeys {
+		writeError(w, http.StatusRequestEntityTooLarge, codeBadRequest, "too many keys in bulk write")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().Unix()
+	ns := s.kvNamespace(r.PathValue("namespace"))
+	result := kvBulkResult{UnsuccessfulKeys: []string{}}
+	for _, pair := range pairs {
+		entry := &kvEntry{value: []byte(pair.Value), expiration: pair.Expiration}
+		if pair.Base64 {
+			value, err := base64.StdEncoding.DecodeString(pair.Value)
+			if err != nil {
+				result.UnsuccessfulKeys = append(result.UnsuccessfulKeys, pair.Key)
+				continue
+			}
+			entry.value = value
+		}
+		if pair.ExpirationTTL != 0 {
+			entry.expiration = now + pair.ExpirationTTL
+		}
+		if len(pair.Metadata) > 0 && string(pair.Metadata) != "null" {
+			entry.metadata = pair.Metadata
+		}
+
+		if pair.Key == "" || (entry.expiration != 0 && entry.expiration < now+60) || len(entry.metadata) > kvMaxMetadataSize {
+			result.UnsuccessfulKeys = append(result.UnsuccessfulKeys, pair.Key)
+			continue
+		}
+
+		ns[pair.Key] = entry
+		result.SuccessfulKeyCount++
+	}
+
+	writeResult(w, result)
+}
+
+func (s *Server) kvBulkDelete(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	if err := json.NewDecoder(r.Body).Decode(&keys); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(keys) > kvMaxBulkDeleteKeys {
+		writeError(w, http.StatusRequestEntityTooLarge, codeBadRequest, "too many keys in bulk delete")
+		return
+	}
+
+	s.mu.Lock()
+	for _, key := range keys {
+		delete(s.kv[r.PathValue("namespace")], key)
+	}
+	s.mu.Unlock()
+
+	writeResult(w, kvBulkResult{SuccessfulKeyCount: len(keys), UnsuccessfulKeys: []string{}})
+}
+
+func (s *Server) kvBulkGet(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Keys         []string `json:"keys"`
+		Type         string   `json:"type"`
+		WithMetadata bool     `json:"withMetadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(body.Keys) == 0 || len(body.Keys) > kvMaxBulkGetKeys {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "between 1 and 100 keys must be requested")
+		return
+	}
+	if body.Type != "" && body.Type != "text" && body.Type != "json" {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "type must be text or json")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	values := make(map[string]interface{}, len(body.Keys))
+	for _, key := range body.Keys {
+		entry, ok := s.kv[r.PathValue("namespace")][key]
+		if !ok || entry.expired(now) {
+			values[key] = nil
+			continue
+		}
+
+		var value interface{} = string(entry.value)
+		if body.Type == "json" {
+			if !json.Valid(entry.value) {
+				writeError(w, http.StatusBadRequest, codeBadRequest, "value of "+key+" is not valid JSON")
+				return
+			}
+			value = json.RawMessage(entry.value)
+		}
+
+		if body.WithMetadata {
+			value = map[string]interface{}{"value": value, "metadata": entry.metadata}
+		}
+		values[key] = value
+	}
+
+	writeResult(w, map[string]interface{}{"values": values})
+}
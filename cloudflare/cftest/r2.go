@@ -0,0 +1,140 @@
+package cftest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type r2Object struct {
+	data         []byte
+	contentType  string
+	etag         string
+	lastModified time.Time
+	metadata     map[string]string
+}
+
+func (o *r2Object) info(key string) r2ObjectInfo {
+	return r2ObjectInfo{
+		Key:          key,
+		Size:         len(o.data),
+		ETag:         o.etag,
+		ContentType:  o.contentType,
+		LastModified: o.lastModified.UTC().Format(time.RFC3339Nano),
+		Metadata:     o.metadata,
+	}
+}
+
+type r2ObjectInfo struct {
+	Key          string            `json:"key"`
+	Size         int               `json:"size"`
+	ETag         string            `json:"etag"`
+	ContentType  string            `json:"content_type,omitempty"`
+	LastModified string            `json:"last_modified"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+func (s *Server) routeR2(mux *http.ServeMux) {
+	const prefix = "/accounts/{account}/r2/buckets/{bucket}/objects"
+
+	mux.HandleFunc("GET "+prefix, s.account(s.r2List))
+	mux.HandleFunc("GET "+prefix+"/{key...}", s.account(s.r2Get))
+	mux.HandleFunc("PUT "+prefix+"/{key...}", s.account(s.r2Put))
+	mux.HandleFunc("DELETE "+prefix+"/{key...}", s.account(s.r2Delete))
+}
+
+// R2Object returns the contents of an object in an R2 bucket, for assertions
+func (s *Server) R2Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.r2[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), object.data...), true
+}
+
+func (s *Server) r2List(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	s.mu.Lock()
+	objects := []r2ObjectInfo{}
+	for key, object := range s.r2[r.PathValue("bucket")] {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, object.info(key))
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	writeResult(w, objects)
+}
+
+func (s *Server) r2Get(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	object, ok := s.r2[r.PathValue("bucket")][r.PathValue("key")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, codeR2NoSuchKey, "The specified key does not exist.")
+		return
+	}
+
+	for k, v := range object.metadata {
+		w.Header().Set("X-Metadata-"+k, v)
+	}
+	if object.contentType != "" {
+		w.Header().Set("Content-Type", object.contentType)
+	}
+	w.Header().Set("ETag", `"`+object.etag+`"`)
+	w.Header().Set("Last-Modified", object.lastModified.UTC().Format(http.TimeFormat))
+	w.Write(object.data)
+}
+
+func (s *Server) r2Put(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, "error reading body")
+		return
+	}
+
+	sum := md5.Sum(data)
+	object := &r2Object{
+		data:         data,
+		contentType:  r.Header.Get("Content-Type"),
+		etag:         hex.EncodeToString(sum[:]),
+		lastModified: s.now(),
+	}
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, "X-Metadata-") {
+			if object.metadata == nil {
+				object.metadata = make(map[string]string)
+			}
+			object.metadata[strings.ToLower(strings.TrimPrefix(name, "X-Metadata-"))] = values[0]
+		}
+	}
+
+	bucket, key := r.PathValue("bucket"), r.PathValue("key")
+
+	s.mu.Lock()
+	if s.r2[bucket] == nil {
+		s.r2[bucket] = make(map[string]*r2Object)
+	}
+	s.r2[bucket][key] = object
+	s.mu.Unlock()
+
+	writeResult(w, object.info(key))
+}
+
+func (s *Server) r2Delete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	delete(s.r2[r.PathValue("bucket")], r.PathValue("key"))
+	s.mu.Unlock()
+
+	writeResult(w, nil)
+}
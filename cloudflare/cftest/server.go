@@ -0,0 +1,201 @@
+// Package cftest provides an in-memory fake of the Cloudflare API for tests.
+//
+// The fake implements the KV, R2 and D1 endpoints used by the cloudflare
+// package, with D1 databases backed by an embedded SQLite engine, so code that
+// uses a cloudflare.Storage can be tested offline:
+//
+//	func TestSignup(t *testing.T) {
+//	    srv := cftest.NewServer(t)
+//	    storage := cloudflare.NewStorage(srv.Config())
+//	    dbID := srv.CreateD1Database("app")
+//
+//	    _, err := storage.D1.ExecuteQuery(ctx, dbID, "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)", nil)
+//	    ...
+//	}
+//
+// D1 export, import and Time Travel are not emulated and respond with an error.
+package cftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+
+// Default credentials accepted by a Server
+const (
+	DefaultAccountID = "cftest-account"
+	DefaultAPIToken  = "cftest-token"
+)
+
+// Cloudflare error codes returned by the fake
+const (
+	codeAuthenticationError = 10000
+	codeKVKeyNotFound       = 10009
+	codeKVNamespaceNotFound = 10013
+	codeKVNamespaceExists   = 10014
+	codeR2NoSuchKey         = 10007
+	codeD1NotFound          = 7404
+	codeD1QueryError        = 7500
+	codeBadRequest          = 7400
+	codeNoRoute             = 7003
+	codeNotImplemented      = 7501
+)
+
+// Server is a fake Cloudflare API backed by an httptest.Server
+type Server struct {
+	// URL is the base URL of the fake, to be used as CloudflareConfig.BaseURL
+	URL string
+
+	AccountID string
+	APIToken  string
+
+	srv *httptest.Server
+	mu  sync.Mutex
+
+	// kv maps namespace IDs to their keys
+	kv map[string]map[string]*kvEntry
+
+	// kvTitles maps namespace IDs to their titles. Namespaces created
+	// implicitly by writing to them are titled with their ID.
+	kvTitles map[string]string
+
+	// r2 maps bucket names to their objects
+	r2 map[string]map[string]*r2Object
+
+	// d1 maps database IDs to databases
+	d1 map[string]*d1Database
+
+	nextID int
+	now    func() time.Time
+}
+
+// NewServer starts a fake Cloudflare API that is closed when tb's test ends
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s := newServer()
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+func newServer() *Server {
+	s := &Server{
+		AccountID: DefaultAccountID,
+		APIToken:  DefaultAPIToken,
+		kv:        make(map[string]map[string]*kvEntry),
+		kvTitles:  make(map[string]string),
+		r2:        make(map[string]map[string]*r2Object),
+		d1:        make(map[string]*d1Database),
+		now:       time.Now,
+	}
+
+	mux := http.NewServeMux()
+	s.routeKV(mux)
+	s.routeR2(mux)
+	s.routeD1(mux)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, codeNoRoute, "No route for that URI")
+	})
+
+	s.srv = httptest.NewServer(s.authenticate(mux))
+	s.URL = s.srv.URL
+
+	return s
+}
+
+// Close shuts down the server and closes every D1 database
+func (s *Server) Close() {
+	s.srv.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, db := range s.d1 {
+		db.db.Close()
+	}
+	s.d1 = make(map[string]*d1Database)
+}
+
+// Config returns a CloudflareConfig that points at the server. Retries are
+// disabled so that failures surface immediately in tests.
+func (s *Server) Config() *cloudflare.CloudflareConfig {
+	config := cloudflare.NewConfig(s.APIToken, s.AccountID)
+	config.BaseURL = s.URL
+	config.Retry = nil
+	return config
+}
+
+// Storage returns a cloudflare.Storage using Config
+func (s *Server) Storage(opts ...cloudflare.Option) *cloudflare.Storage {
+	return cloudflare.NewStorage(s.Config(), opts...)
+}
+
+// authenticate rejects requests without the server's API token or for another account
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cf-Ray", fmt.Sprintf("%016x-CFT", time.Now().UnixNano()))
+
+		if r.Header.Get("Authorization") != "Bearer "+s.APIToken {
+			writeError(w, http.StatusUnauthorized, codeAuthenticationError, "Authentication error")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// account wraps a handler to check the account ID in the path
+func (s *Server) account(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("account") != s.AccountID {
+			writeError(w, http.StatusNotFound, codeNoRoute, "account not found")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// newID returns a new UUID-shaped identifier
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012x", s.nextID)
+}
+
+// envelope is the response format of the Cloudflare API
+type envelope struct {
+	Success    bool                     `json:"success"`
+	Errors     []cloudflare.ErrorDetail `json:"errors"`
+	Messages   []cloudflare.ErrorDetail `json:"messages"`
+	Result     interface{}              `json:"result"`
+	ResultInfo interface{}              `json:"result_info,omitempty"`
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	writeEnvelope(w, http.StatusOK, envelope{Success: true, Result: result})
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	writeEnvelope(w, status, envelope{
+		Errors: []cloudflare.ErrorDetail{{Code: code, Message: message}},
+	})
+}
+
+func writeEnvelope(w http.ResponseWriter, status int, e envelope) {
+	if e.Errors == nil {
+		e.Errors = []cloudflare.ErrorDetail{}
+	}
+	if e.Messages == nil {
+		e.Messages = []cloudflare.ErrorDetail{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
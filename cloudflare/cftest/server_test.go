@@ -0,0 +1,262 @@
+package cftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+
+// response is a decoded Cloudflare API envelope
+type response struct {
+	Status     int
+	Header     http.Header
+	Body       []byte
+	Success    bool                     `json:"success"`
+	Errors     []cloudflare.ErrorDetail `json:"errors"`
+	Result     json.RawMessage          `json:"result"`
+	ResultInfo json.RawMessage          `json:"result_info"`
+}
+
+// call sends an authenticated request for the account of s and decodes the envelope,
+// if the response is JSON
+func call(t *testing.T, s *Server, method, path, body string) *response {
+	t.Helper()
+	return callWithToken(t, s, method, path, body, s.APIToken)
+}
+
+func callWithToken(t *testing.T, s *Server, method, path, body, token string) *response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, s.URL+"/accounts/"+s.AccountID+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[") {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	r := &response{Status: resp.StatusCode, Header: resp.Header}
+	if r.Body, err = io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(r.Body, r); err != nil {
+			t.Fatalf("invalid envelope %s: %v", r.Body, err)
+		}
+	}
+
+	return r
+}
+
+// expectError checks that r is an error envelope with the given status and code
+func expectError(t *testing.T, r *response, status, code int) {
+	t.Helper()
+
+	if r.Status != status || r.Success || len(r.Errors) != 1 || r.Errors[0].Code != code {
+		t.Errorf("got %d %s, want status %d with error code %d", r.Status, r.Body, status, code)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := NewServer(t)
+
+	r := callWithToken(t, s, http.MethodGet, "/d1/database", "", "wrong")
+	expectError(t, r, http.StatusUnauthorized, codeAuthenticationError)
+
+	r = call(t, s, http.MethodGet, "/unknown", "")
+	expectError(t, r, http.StatusNotFound, codeNoRoute)
+
+	if r.Header.Get("Cf-Ray") == "" {
+		t.Error("response has no Cf-Ray header")
+	}
+}
+
+func TestKVValues(t *testing.T) {
+	s := NewServer(t)
+	namespaceID := s.CreateKVNamespace("test")
+	values := "/storage/kv/namespaces/" + namespaceID + "/values/"
+
+	r := call(t, s, http.MethodPut, values+"greeting?expiration_ttl=60", "hello")
+	if r.Status != http.StatusOK || !r.Success {
+		t.Fatalf("write: %d %s", r.Status, r.Body)
+	}
+
+	r = call(t, s, http.MethodGet, values+"greeting", "")
+	if r.Status != http.StatusOK || string(r.Body) != "hello" || r.Header.Get("Expiration") == "" {
+		t.Errorf("read: %d %q with Expiration %q", r.Status, r.Body, r.Header.Get("Expiration"))
+	}
+
+	r = call(t, s, http.MethodPut, values+"short?expiration_ttl=30", "x")
+	expectError(t, r, http.StatusBadRequest, codeBadRequest)
+
+	// Expired keys disappear
+	s.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	r = call(t, s, http.MethodGet, values+"greeting", "")
+	expectError(t, r, http.StatusNotFound, codeKVKeyNotFound)
+}
+
+func TestKVListKeysCursor(t *testing.T) {
+	s := NewServer(t)
+	namespaceID := s.CreateKVNamespace("test")
+	for _, key := range []string{"c", "a", "b"} {
+		s.SetKVValue(namespaceID, key, []byte("x"))
+	}
+	keys := "/storage/kv/namespaces/" + namespaceID + "/keys?limit=2"
+
+	var names []string
+	var info struct {
+		Count  int    `json:"count"`
+		Cursor string `json:"cursor"`
+	}
+	for page := 0; page == 0 || info.Cursor != ""; page++ {
+		path := keys
+		if info.Cursor != "" {
+			path += "&cursor=" + info.Cursor
+		}
+		r := call(t, s, http.MethodGet, path, "")
+
+		var result []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(r.Result, &result); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(r.ResultInfo, &info); err != nil {
+			t.Fatal(err)
+		}
+		if info.Count != len(result) {
+			t.Errorf("count = %d for %d keys", info.Count, len(result))
+		}
+		for _, key := range result {
+			names = append(names, key.Name)
+		}
+	}
+
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("keys = %v, want [a b c]", names)
+	}
+
+	r := call(t, s, http.MethodGet, keys+"&cursor=!", "")
+	expectError(t, r, http.StatusBadRequest, codeBadRequest)
+}
+
+func TestKVBulkWriteLimit(t *testing.T) {
+	s := NewServer(t)
+	namespaceID := s.CreateKVNamespace("test")
+
+	pairs := make([]map[string]string, kvMaxBulkWriteKeys+1)
+	for i := range pairs {
+		pairs[i] = map[string]string{"key": "k", "value": "v"}
+	}
+	body, _ := json.Marshal(pairs)
+
+	r := call(t, s, http.MethodPut, "/storage/kv/namespaces/"+namespaceID+"/bulk", string(body))
+	expectError(t, r, http.StatusRequestEntityTooLarge, codeBadRequest)
+}
+
+func TestD1Query(t *testing.T) {
+	s := NewServer(t)
+	query := "/d1/database/" + s.CreateD1Database("app") + "/query"
+
+	// Like D1, parameters are not allowed with several statements
+	r := call(t, s, http.MethodPost, query, `{"sql": "SELECT ?; SELECT ?", "params": [1, 2]}`)
+	expectError(t, r, http.StatusBadRequest, codeD1QueryError)
+
+	r = call(t, s, http.MethodPost, query, `{"batch": [
+		{"sql": "CREATE TABLE t (id INTEGER PRIMARY KEY, data BLOB); CREATE INDEX t_data ON t (data)"},
+		{"sql": "INSERT INTO t (data) VALUES (?)", "params": [[1, 2]]},
+		{"sql": "SELECT id, typeof(data) AS type FROM t"}
+	]}`)
+	if r.Status != http.StatusOK || !r.Success {
+		t.Fatalf("batch: %d %s", r.Status, r.Body)
+	}
+
+	var results []struct {
+		Results []map[string]interface{} `json:"results"`
+		Success bool                     `json:"success"`
+		Meta    struct {
+			Changes   int `json:"changes"`
+			LastRowID int `json:"last_row_id"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(r.Result, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want one per SQL statement", len(results))
+	}
+	if results[2].Meta.Changes != 1 || results[2].Meta.LastRowID != 1 {
+		t.Errorf("insert meta = %+v, want 1 change with row ID 1", results[2].Meta)
+	}
+	if rows := results[3].Results; len(rows) != 1 || rows[0]["type"] != "blob" {
+		t.Errorf("rows = %v, want one blob", rows)
+	}
+}
+
+func TestD1ErrorEnvelope(t *testing.T) {
+	s := NewServer(t)
+	query := "/d1/database/" + s.CreateD1Database("app") + "/query"
+
+	r := call(t, s, http.MethodPost, query, `{"batch": [
+		{"sql": "CREATE TABLE t (id INTEGER PRIMARY KEY)"},
+		{"sql": "INSERT INTO missing VALUES (1)"}
+	]}`)
+	expectError(t, r, http.StatusBadRequest, codeD1QueryError)
+	if len(r.Result) != 0 && !bytes.Equal(r.Result, []byte("null")) {
+		t.Errorf("error envelope has a result: %s", r.Result)
+	}
+
+	// The batch was rolled back
+	r = call(t, s, http.MethodPost, query, `{"sql": "SELECT name FROM sqlite_master WHERE name = 't'"}`)
+	var results []struct {
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(r.Result, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Results) != 0 {
+		t.Errorf("table of a failed batch exists: %s", r.Body)
+	}
+
+	r = call(t, s, http.MethodPost, "/d1/database/missing/query", `{"sql": "SELECT 1"}`)
+	expectError(t, r, http.StatusNotFound, codeD1NotFound)
+
+	r = call(t, s, http.MethodGet, "/d1/database/"+s.CreateD1Database("other")+"/time_travel/bookmark", "")
+	expectError(t, r, http.StatusNotImplemented, codeNotImplemented)
+}
+
+func TestR2Objects(t *testing.T) {
+	s := NewServer(t)
+	objects := "/r2/buckets/media/objects/"
+
+	r := call(t, s, http.MethodPut, objects+"images/a.png", "png")
+	if r.Status != http.StatusOK {
+		t.Fatalf("put: %d %s", r.Status, r.Body)
+	}
+
+	if data, ok := s.R2Object("media", "images/a.png"); !ok || string(data) != "png" {
+		t.Errorf("R2Object = %q, %v", data, ok)
+	}
+
+	r = call(t, s, http.MethodGet, objects+"images/a.png", "")
+	if r.Status != http.StatusOK || string(r.Body) != "png" {
+		t.Errorf("get: %d %q", r.Status, r.Body)
+	}
+
+	call(t, s, http.MethodDelete, objects+"images/a.png", "")
+	r = call(t, s, http.MethodGet, objects+"images/a.png", "")
+	expectError(t, r, http.StatusNotFound, codeR2NoSuchKey)
+}
@@ -1,8 +1,16 @@
 package cloudflare
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CloudflareConfig holds the credentials and endpoint information for Cloudflare services
@@ -16,8 +24,21 @@ type CloudflareConfig struct {
 	// Base URL for Cloudflare API
 	BaseURL string
 
-	// Timeout for HTTP requests
+	// Timeout for HTTP requests, including any retries
 	Timeout time.Duration
+
+	// Retry controls retries of rate limited and failed requests; nil disables them
+	Retry *RetryPolicy
+
+	// TracerProvider and MeterProvider enable OpenTelemetry spans and metrics
+	// for every client call; nil uses no-op providers
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+
+	// Logger receives debug logs for requests and responses and warnings for
+	// failures and retries; nil disables logging. The API token and request
+	// bodies are never logged.
+	Logger *slog.Logger
 }
 
 // NewConfig creates a CloudflareConfig with sensible defaults
@@ -27,12 +48,72 @@ func NewConfig(apiToken, accountID string) *CloudflareConfig {
 		AccountID: accountID,
 		BaseURL:   "https://api.cloudflare.com/client/v4",
 		Timeout:   30 * time.Second,
+		Retry:     DefaultRetryPolicy(),
+	}
+}
+
+// newAPIRequest creates a request to the Cloudflare API authenticated with the config's token
+func newAPIRequest(ctx context.Context, config *CloudflareConfig, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+config.APIToken)
+
+	return req, nil
+}
+
+// doAPIRequest executes req and returns the response if its status is 2xx.
+// Otherwise the body is closed and an *APIError describing it is returned.
+func doAPIRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
 	}
+
+	return resp, nil
+}
+
+// decodeAPIResponse decodes the response envelope and closes the body. The
+// result field is decoded into result, which may be nil. An envelope with
+// success set to false is returned as an *APIError.
+func decodeAPIResponse(resp *http.Response, result interface{}) error {
+	return decodePagedAPIResponse(resp, result, nil)
 }
 
-// createHTTPClient creates an HTTP client with the config's timeout
-func createHTTPClient(config *CloudflareConfig) *http.Client {
-	return &http.Client{
-		Timeout: config.Timeout,
+// decodePagedAPIResponse is decodeAPIResponse for list endpoints, decoding
+// the result_info field of the envelope into resultInfo, which may be nil
+func decodePagedAPIResponse(resp *http.Response, result, resultInfo interface{}) error {
+	defer resp.Body.Close()
+
+	response := struct {
+		Errors     []ErrorDetail `json:"errors"`
+		Messages   []ErrorDetail `json:"messages"`
+		Result     interface{}   `json:"result"`
+		ResultInfo interface{}   `json:"result_info"`
+		Success    bool          `json:"success"`
+	}{
+		Result:     result,
+		ResultInfo: resultInfo,
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
 	}
+
+	if !response.Success {
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Errors:     response.Errors,
+			Messages:   response.Messages,
+			RayID:      resp.Header.Get("Cf-Ray"),
+		}
+	}
+
+	return nil
 }
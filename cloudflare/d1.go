@@ -7,44 +7,44 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/internal/sqlsplit"
 )
 
 // D1Client provides access to Cloudflare D1 SQL database
 type D1Client struct {
 	config *CloudflareConfig
 	client *http.Client
+	tel    *telemetry
+
+	// transfer sends export and import files to and from signed URLs
+	transfer *http.Client
+}
+
+// D1Meta holds the execution statistics D1 reports for a statement
+type D1Meta struct {
+	ChangedDB       bool    `json:"changed_db"`
+	Changes         int     `json:"changes"`
+	Duration        float32 `json:"duration"`
+	LastRowID       int     `json:"last_row_id"`
+	RowsRead        int     `json:"rows_read"`
+	RowsWritten     int     `json:"rows_written"`
+	ServedByPrimary bool    `json:"served_by_primary"`
+	ServedByRegion  string  `json:"served_by_region"`
+	SizeAfter       int     `json:"size_after"`
+	Timings         struct {
+		SQLDurationMS float32 `json:"sql_duration_ms"`
+	} `json:"timings"`
 }
 
 // D1ResponseItem represents a single item in the result array
 type D1ResponseItem struct {
-    Meta struct {
-        ChangedDB        bool   `json:"changed_db"`
-        Changes          int    `json:"changes"`
-        Duration         float32    `json:"duration"`
-        LastRowID        int    `json:"last_row_id"`
-        RowsRead         int    `json:"rows_read"`
-        RowsWritten      int    `json:"rows_written"`
-        ServedByPrimary  bool   `json:"served_by_primary"`
-        ServedByRegion   string `json:"served_by_region"`
-        SizeAfter        int    `json:"size_after"`
-        Timings struct {
-            SQLDurationMS float32 `json:"sql_duration_ms"`
-        } `json:"timings"`
-    } `json:"meta"`
+    Meta    D1Meta                   `json:"meta"`
     Results []map[string]interface{} `json:"results"`
     Success bool                     `json:"success"`
 }
 
-// D1Error represents an error in the response
-type D1Error struct {
-    Code             int    `json:"code"`
-    Message          string `json:"message"`
-    DocumentationURL string `json:"documentation_url"`
-    Source struct {
-        Pointer string `json:"pointer"`
-    } `json:"source"`
-}
-
 /*
 * https://developers.cloudflare.com/api/resources/d1/subresources/database/methods/query/
 */
@@ -57,90 +57,282 @@ type D1Response struct {
     Success  bool            `json:"success"`
 }
 
-// NewD1Client creates a new D1Client with the provided configuration
-func NewD1Client(config *CloudflareConfig) *D1Client {
+// D1Statement is a single SQL statement with its positional parameters.
+// A []byte parameter is bound as a blob.
+type D1Statement struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params,omitempty"`
+}
+
+// MarshalJSON encodes []byte parameters as arrays of byte values, which D1
+// binds as blobs; encoding/json would send them as base64 text instead
+func (s D1Statement) MarshalJSON() ([]byte, error) {
+	type statement D1Statement
+
+	// Copy the parameters before replacing any, since they belong to the caller
+	params, copied := s.Params, false
+	for i, param := range s.Params {
+		b, ok := param.([]byte)
+		if !ok {
+			continue
+		}
+		if !copied {
+			params, copied = append([]interface{}(nil), s.Params...), true
+		}
+		params[i] = blobParam(b)
+	}
+
+	return json.Marshal(statement{SQL: s.SQL, Params: params})
+}
+
+// blobParam encodes b as an array of byte values. A nil slice is NULL.
+func blobParam(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	blob := make([]int, len(b))
+	for i, x := range b {
+		blob[i] = int(x)
+	}
+	return blob
+}
+
+// D1BatchError reports which statement of a batch caused it to fail
+type D1BatchError struct {
+	// Index of the failing statement in the batch, or -1 if D1 did not say
+	// which one failed. It is only known when the error response includes
+	// the results of the statements up to the failing one.
+	Index int
+
+	// Err is the underlying error
+	Err error
+}
+
+func (e *D1BatchError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("batch failed: %v", e.Err)
+	}
+	return fmt.Sprintf("batch statement %d failed: %v", e.Index, e.Err)
+}
+
+func (e *D1BatchError) Unwrap() error {
+	return e.Err
+}
+
+// NewD1Client creates a new D1Client with the provided configuration and options
+func NewD1Client(config *CloudflareConfig, opts ...Option) *D1Client {
 	if config.BaseURL == "" {
 		config.BaseURL = "https://api.cloudflare.com/client/v4"
 	}
 
 	return &D1Client{
-		config: config,
-		client: createHTTPClient(config),
-	}
-}
-
-// ExecuteQuery executes a SQL query on a D1 database
-func (d *D1Client) ExecuteQuery(ctx context.Context, databaseID, query string, params []interface{}) (*D1ResponseItem, error) {
-    // Create request URL
-    url := fmt.Sprintf("%s/accounts/%s/d1/database/%s/query", 
-        d.config.BaseURL, d.config.AccountID, databaseID)
-    
-    // Format parameters as expected by the API
-    type QueryParams struct {
-        Params []interface{} `json:"params,omitempty"`
-    }
-    
-    // Prepare request body
-    requestBody := struct {
-        SQL    string      `json:"sql"`
-        Params interface{} `json:"params,omitempty"`
-    }{
-        SQL: query,
-    }
-    
-    // Only add params if they exist
-    if params != nil && len(params) > 0 {
-        requestBody.Params = params
-    }
-    
-    // Marshal request body
-    jsonBody, err := json.Marshal(requestBody)
-    if err != nil {
-        return nil, fmt.Errorf("error marshaling request: %w", err)
-    }
-    
-    // Create request
-    req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
-    if err != nil {
-        return nil, fmt.Errorf("error creating request: %w", err)
-    }
-    
-    // Set headers
-    req.Header.Set("Content-Type", "application/json")
-    req.Header.Set("Authorization", "Bearer "+d.config.APIToken)
-    
-    // Execute request
-    resp, err := d.client.Do(req)
-    if err != nil {
-        return nil, fmt.Errorf("error making request: %w", err)
-    }
-    defer resp.Body.Close()
-    
-    // Check status code
-    if resp.StatusCode != http.StatusOK {
-        bodyBytes, _ := io.ReadAll(resp.Body)
-        return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
-    }
-    
-    // Decode response
-    var response D1Response
-    if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-        return nil, fmt.Errorf("error decoding response: %w", err)
-    }
-    
-    // Check for success
-    if !response.Success {
-        errMsg := "request was not successful"
-        if len(response.Errors) > 0 {
-            errMsg = fmt.Sprintf("%s: %s", errMsg, response.Errors[0].Message)
-        }
-        return nil, fmt.Errorf(errMsg)
-    }
-    
-    // Return the first result item
-    if len(response.Result) == 0 {
-        return nil, fmt.Errorf("empty result set")
-    }
-    
-    return &response.Result[0], nil
+		config:   config,
+		client:   createHTTPClient(config, opts...),
+		tel:      newTelemetry(config, serviceD1),
+		transfer: createTransferClient(opts...),
+	}
+}
+
+// ExecuteQuery executes a SQL query on a D1 database.
+// If query contains several statements, only the result of the first one
+// is returned; use Batch to get every result.
+func (d *D1Client) ExecuteQuery(ctx context.Context, databaseID, query string, params []interface{}) (_ *D1ResponseItem, err error) {
+	ctx, op := d.tel.start(ctx, "ExecuteQuery", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	stmt := D1Statement{SQL: query, Params: params}
+	if readOnly(stmt) {
+		ctx = WithIdempotent(ctx)
+	}
+
+	results, err := d.query(ctx, databaseID, stmt)
+	if err != nil {
+		return nil, err
+	}
+	op.recordD1(resultMetas(results)...)
+
+	// Return the first result item
+	if len(results) == 0 {
+		return nil, fmt.Errorf("empty result set")
+	}
+
+	return &results[0], nil
+}
+
+// Batch executes several statements in a single request.
+// D1 runs a batch as one transaction, so either every statement is applied or none is.
+// Results are returned in the same order as statements. A statement whose SQL
+// contains several statements may produce a result for each of them, so there
+// can be more results than statements. When a statement fails the returned
+// error is a *D1BatchError identifying it.
+// Example usage:
+//
+//	results, err := d1Client.Batch(ctx, databaseID, []cloudflare.D1Statement{
+//	    {SQL: "UPDATE accounts SET balance = balance - ? WHERE id = ?", Params: []interface{}{100, 1}},
+//	    {SQL: "UPDATE accounts SET balance = balance + ? WHERE id = ?", Params: []interface{}{100, 2}},
+//	})
+func (d *D1Client) Batch(ctx context.Context, databaseID string, statements []D1Statement) (_ []D1ResponseItem, err error) {
+	ctx, op := d.tel.start(ctx, "Batch", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("batch is empty")
+	}
+
+	body := struct {
+		Batch []D1Statement `json:"batch"`
+	}{
+		Batch: statements,
+	}
+
+	if readOnly(statements...) {
+		ctx = WithIdempotent(ctx)
+	}
+
+	results, err := d.query(ctx, databaseID, body)
+	if err != nil {
+		return nil, &D1BatchError{Index: statementIndex(statements, failedResult(results)), Err: err}
+	}
+	op.recordD1(resultMetas(results)...)
+
+	for i, result := range results {
+		if !result.Success {
+			return nil, &D1BatchError{Index: statementIndex(statements, i), Err: fmt.Errorf("statement was not successful")}
+		}
+	}
+
+	return results, nil
+}
+
+// resultMetas returns the meta of every result
+func resultMetas(results []D1ResponseItem) []D1Meta {
+	metas := make([]D1Meta, len(results))
+	for i, result := range results {
+		metas[i] = result.Meta
+	}
+	return metas
+}
+
+// failedResult returns the index of the first unsuccessful result, or -1
+func failedResult(results []D1ResponseItem) int {
+	for i, result := range results {
+		if !result.Success {
+			return i
+		}
+	}
+	return -1
+}
+
+// statementIndex converts the index of a result into the index of the statement
+// that produced it, or -1. A statement whose SQL contains several statements
+// produces a result for each of them.
+func statementIndex(statements []D1Statement, result int) int {
+	if result < 0 {
+		return -1
+	}
+	for i, stmt := range statements {
+		result -= max(len(sqlsplit.Split(stmt.SQL)), 1)
+		if result < 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// readOnly reports whether every statement is a single SELECT or EXPLAIN,
+// which cannot change the database and is therefore safe to retry
+func readOnly(statements ...D1Statement) bool {
+	for _, stmt := range statements {
+		sql := strings.TrimRight(strings.TrimSpace(stmt.SQL), "; \t\r\n")
+		if strings.Contains(sql, ";") {
+			return false
+		}
+
+		end := strings.IndexFunc(sql, func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z')
+		})
+		if end < 0 {
+			end = len(sql)
+		}
+
+		switch strings.ToUpper(sql[:end]) {
+		case "SELECT", "EXPLAIN":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// query posts a single statement or a batch to the query endpoint and returns
+// every result item. D1 reports a failed statement with a 4xx status and may
+// include the results up to and including it, so results decoded from an
+// error response are returned with the error.
+func (d *D1Client) query(ctx context.Context, databaseID string, body interface{}) ([]D1ResponseItem, error) {
+	url := fmt.Sprintf("%s/accounts/%s/d1/database/%s/query",
+		d.config.BaseURL, d.config.AccountID, databaseID)
+
+	req, err := d.newRequest(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+		var envelope struct {
+			Result []D1ResponseItem `json:"result"`
+		}
+		json.Unmarshal(errBody, &envelope)
+
+		return envelope.Result, newAPIErrorFromBody(resp, errBody)
+	}
+
+	var results []D1ResponseItem
+	err = decodeAPIResponse(resp, &results)
+	return results, err
+}
+
+// do sends a JSON request to the D1 API and decodes the result field of the
+// response envelope into result, which may be nil. API failures are returned as *APIError.
+func (d *D1Client) do(ctx context.Context, method, url string, body interface{}, result interface{}) error {
+	req, err := d.newRequest(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doAPIRequest(d.client, req)
+	if err != nil {
+		return err
+	}
+
+	return decodeAPIResponse(resp, result)
+}
+
+// newRequest creates an API request with body, which may be nil, encoded as JSON
+func (d *D1Client) newRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonBody)
+	}
+
+	req, err := newAPIRequest(ctx, d.config, method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
 }
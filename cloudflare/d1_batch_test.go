@@ -0,0 +1,46 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStatementIndex(t *testing.T) {
+	statements := []D1Statement{
+		{SQL: "INSERT INTO a VALUES (1); INSERT INTO a VALUES (2)"},
+		{SQL: "UPDATE a SET x = 1"},
+		{SQL: "-- only the last statement produces a result\nDELETE FROM a;"},
+	}
+
+	tests := []struct {
+		result, want int
+	}{
+		{-1, -1},
+		{0, 0},
+		{1, 0},
+		{2, 1},
+		{3, 2},
+		{4, -1},
+	}
+	for _, tt := range tests {
+		if got := statementIndex(statements, tt.result); got != tt.want {
+			t.Errorf("statementIndex(%d) = %d, want %d", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestStatementMarshalsBlobs(t *testing.T) {
+	params := []interface{}{[]byte{0, 255}, "text", []byte(nil)}
+	stmt := D1Statement{SQL: "INSERT INTO files VALUES (?, ?, ?)", Params: params}
+
+	got, err := json.Marshal(stmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"sql":"INSERT INTO files VALUES (?, ?, ?)","params":[[0,255],"text",null]}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if _, ok := params[0].([]byte); !ok {
+		t.Error("caller's parameters were modified")
+	}
+}
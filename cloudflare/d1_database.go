@@ -0,0 +1,157 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// D1LocationHint is a region hint for where a new D1 database's primary is placed
+type D1LocationHint string
+
+// Location hints accepted by CreateDatabase
+const (
+	D1LocationWesternNorthAmerica D1LocationHint = "wnam"
+	D1LocationEasternNorthAmerica D1LocationHint = "enam"
+	D1LocationWesternEurope       D1LocationHint = "weur"
+	D1LocationEasternEurope       D1LocationHint = "eeur"
+	D1LocationAsiaPacific         D1LocationHint = "apac"
+	D1LocationOceania             D1LocationHint = "oc"
+)
+
+// D1Database describes a D1 database in the account
+type D1Database struct {
+	UUID      string    `json:"uuid"`
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// NumTables is the number of tables in the database
+	NumTables int `json:"num_tables"`
+
+	// FileSize is the size of the database in bytes
+	FileSize int64 `json:"file_size"`
+}
+
+/*
+* https://developers.cloudflare.com/api/resources/d1/subresources/database/
+ */
+
+// d1ListPageSize is the number of databases requested per page when listing
+const d1ListPageSize = 100
+
+// ListDatabases lists every D1 database in the account
+func (d *D1Client) ListDatabases(ctx context.Context) (_ []D1Database, err error) {
+	ctx, op := d.tel.start(ctx, "ListDatabases")
+	defer func() { op.end(err) }()
+
+	return d.listDatabases(ctx, "")
+}
+
+// listDatabases walks every page of the list endpoint, optionally filtered by name
+func (d *D1Client) listDatabases(ctx context.Context, name string) ([]D1Database, error) {
+	var databases []D1Database
+
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", fmt.Sprint(page))
+		query.Set("per_page", fmt.Sprint(d1ListPageSize))
+		if name != "" {
+			query.Set("name", name)
+		}
+
+		urlPath := fmt.Sprintf("%s/accounts/%s/d1/database?%s",
+			d.config.BaseURL, d.config.AccountID, query.Encode())
+
+		var result []D1Database
+		if err := d.do(ctx, http.MethodGet, urlPath, nil, &result); err != nil {
+			return nil, err
+		}
+
+		databases = append(databases, result...)
+		if len(result) < d1ListPageSize {
+			return databases, nil
+		}
+	}
+}
+
+// GetDatabase returns information about a D1 database
+func (d *D1Client) GetDatabase(ctx context.Context, databaseID string) (_ *D1Database, err error) {
+	ctx, op := d.tel.start(ctx, "GetDatabase", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/d1/database/%s",
+		d.config.BaseURL, d.config.AccountID, databaseID)
+
+	var result D1Database
+	if err := d.do(ctx, http.MethodGet, urlPath, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DatabaseByName returns the D1 database with the given name
+// Example usage:
+//
+//	db, err := d1Client.DatabaseByName(ctx, "tenant-42")
+//	if err != nil {
+//	    log.Fatalf("Failed to find database: %v", err)
+//	}
+//	result, err := d1Client.ExecuteQuery(ctx, db.UUID, "SELECT 1", nil)
+func (d *D1Client) DatabaseByName(ctx context.Context, name string) (_ *D1Database, err error) {
+	ctx, op := d.tel.start(ctx, "DatabaseByName")
+	defer func() { op.end(err) }()
+
+	databases, err := d.listDatabases(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The name filter matches substrings, so look for an exact match
+	for i := range databases {
+		if databases[i].Name == name {
+			return &databases[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("database %s %w", name, ErrNotFound)
+}
+
+// CreateDatabase creates a new D1 database. locationHint may be empty to let
+// Cloudflare place the database close to the caller.
+func (d *D1Client) CreateDatabase(ctx context.Context, name string, locationHint D1LocationHint) (_ *D1Database, err error) {
+	ctx, op := d.tel.start(ctx, "CreateDatabase")
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/d1/database",
+		d.config.BaseURL, d.config.AccountID)
+
+	body := struct {
+		Name                string         `json:"name"`
+		PrimaryLocationHint D1LocationHint `json:"primary_location_hint,omitempty"`
+	}{
+		Name:                name,
+		PrimaryLocationHint: locationHint,
+	}
+
+	var result D1Database
+	if err := d.do(ctx, http.MethodPost, urlPath, body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DeleteDatabase deletes a D1 database and all of its data
+func (d *D1Client) DeleteDatabase(ctx context.Context, databaseID string) (err error) {
+	ctx, op := d.tel.start(ctx, "DeleteDatabase", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/d1/database/%s",
+		d.config.BaseURL, d.config.AccountID, databaseID)
+
+	return d.do(ctx, http.MethodDelete, urlPath, nil, nil)
+}
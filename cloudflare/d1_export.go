@@ -0,0 +1,317 @@
+package cloudflare
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+* https://developers.cloudflare.com/api/resources/d1/subresources/database/methods/export/
+* https://developers.cloudflare.com/api/resources/d1/subresources/database/methods/import/
+ */
+
+// defaultD1PollInterval is how often long running export and import operations are polled
+const defaultD1PollInterval = time.Second
+
+// D1ExportOptions controls what ExportDatabase includes in the dump
+type D1ExportOptions struct {
+	// NoData exports only the schema
+	NoData bool
+
+	// NoSchema exports only the data
+	NoSchema bool
+
+	// Tables limits the export to the given tables
+	Tables []string
+
+	// PollInterval is how often the export status is checked, defaulting to one second
+	PollInterval time.Duration
+}
+
+// D1ExportResult describes a completed export
+type D1ExportResult struct {
+	// Filename is the name Cloudflare gave the dump file
+	Filename string
+
+	// AtBookmark is the Time Travel bookmark the dump was taken at
+	AtBookmark string
+
+	// Size is the number of bytes written
+	Size int64
+}
+
+// D1ImportStatus is the state of an import reported while it runs
+type D1ImportStatus struct {
+	// Status is "active" while the import runs, then "complete" or "error"
+	Status     string   `json:"status"`
+	AtBookmark string   `json:"at_bookmark"`
+	Messages   []string `json:"messages"`
+	Error      string   `json:"error"`
+	Result     struct {
+		FinalBookmark string `json:"final_bookmark"`
+		NumQueries    int    `json:"num_queries"`
+		Meta          D1Meta `json:"meta"`
+	} `json:"result"`
+}
+
+// D1ImportOptions controls ImportDatabase
+type D1ImportOptions struct {
+	// PollInterval is how often the import status is checked, defaulting to one second
+	PollInterval time.Duration
+
+	// Progress, if set, is called with every status update while the import runs
+	Progress func(D1ImportStatus)
+}
+
+// D1ImportResult describes a completed import
+type D1ImportResult struct {
+	// FinalBookmark is the Time Travel bookmark after the import was applied
+	FinalBookmark string
+
+	// NumQueries is the number of statements executed
+	NumQueries int
+
+	// Meta holds the execution statistics for the import
+	Meta D1Meta
+}
+
+// d1ExportStatus is the result of a call to the export endpoint
+type d1ExportStatus struct {
+	Status     string   `json:"status"`
+	AtBookmark string   `json:"at_bookmark"`
+	Messages   []string `json:"messages"`
+	Error      string   `json:"error"`
+	Result     struct {
+		Filename  string `json:"filename"`
+		SignedURL string `json:"signed_url"`
+	} `json:"result"`
+}
+
+// ExportDatabase dumps a D1 database as SQL and streams the dump to w.
+// The export is started, polled until Cloudflare has produced the dump and
+// then downloaded from its signed URL.
+// Example usage, piping a nightly backup straight into R2:
+//
+//	pr, pw := io.Pipe()
+//	go func() {
+//	    _, err := storage.D1.ExportDatabase(ctx, databaseID, pw, nil)
+//	    pw.CloseWithError(err)
+//	}()
+//	_, err := storage.R2.UploadObject(ctx, "backups", "d1/nightly.sql", pr, "application/sql", nil)
+func (d *D1Client) ExportDatabase(ctx context.Context, databaseID string, w io.Writer, opts *D1ExportOptions) (_ *D1ExportResult, err error) {
+	ctx, op := d.tel.start(ctx, "ExportDatabase", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	if opts == nil {
+		opts = &D1ExportOptions{}
+	}
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/d1/database/%s/export",
+		d.config.BaseURL, d.config.AccountID, databaseID)
+
+	type dumpOptions struct {
+		NoData   bool     `json:"no_data,omitempty"`
+		NoSchema bool     `json:"no_schema,omitempty"`
+		Tables   []string `json:"tables,omitempty"`
+	}
+	body := struct {
+		OutputFormat    string      `json:"output_format"`
+		CurrentBookmark string      `json:"current_bookmark,omitempty"`
+		DumpOptions     dumpOptions `json:"dump_options"`
+	}{
+		OutputFormat: "polling",
+		DumpOptions: dumpOptions{
+			NoData:   opts.NoData,
+			NoSchema: opts.NoSchema,
+			Tables:   opts.Tables,
+		},
+	}
+
+	var status d1ExportStatus
+	for {
+		status = d1ExportStatus{}
+		if err := d.do(ctx, http.MethodPost, urlPath, body, &status); err != nil {
+			return nil, fmt.Errorf("error exporting database: %w", err)
+		}
+
+		if status.Status == "error" {
+			return nil, fmt.Errorf("export failed: %s", status.Error)
+		}
+		if status.Status == "complete" {
+			break
+		}
+
+		body.CurrentBookmark = status.AtBookmark
+		if err := sleepContext(ctx, pollInterval(opts.PollInterval)); err != nil {
+			return nil, err
+		}
+	}
+
+	if status.Result.SignedURL == "" {
+		return nil, errors.New("export completed without a download URL")
+	}
+
+	size, err := d.download(ctx, status.Result.SignedURL, w)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading export: %w", err)
+	}
+
+	return &D1ExportResult{
+		Filename:   status.Result.Filename,
+		AtBookmark: status.AtBookmark,
+		Size:       size,
+	}, nil
+}
+
+// ImportDatabase executes a SQL dump read from r against a D1 database.
+// The dump is buffered to a temporary file to compute the checksum Cloudflare
+// requires, uploaded, ingested and then polled until the import finishes.
+func (d *D1Client) ImportDatabase(ctx context.Context, databaseID string, r io.Reader, opts *D1ImportOptions) (_ *D1ImportResult, err error) {
+	ctx, op := d.tel.start(ctx, "ImportDatabase", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	if opts == nil {
+		opts = &D1ImportOptions{}
+	}
+
+	file, err := os.CreateTemp("", "d1-import-*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading import: %w", err)
+	}
+	etag := hex.EncodeToString(hash.Sum(nil))
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/d1/database/%s/import",
+		d.config.BaseURL, d.config.AccountID, databaseID)
+
+	// Ask for an upload URL for a file with this checksum
+	var initResult struct {
+		UploadURL string `json:"upload_url"`
+		Filename  string `json:"filename"`
+	}
+	initBody := map[string]string{"action": "init", "etag": etag}
+	if err := d.do(ctx, http.MethodPost, urlPath, initBody, &initResult); err != nil {
+		return nil, fmt.Errorf("error starting import: %w", err)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error rewinding import: %w", err)
+	}
+	if err := d.upload(ctx, initResult.UploadURL, file, size, etag); err != nil {
+		return nil, fmt.Errorf("error uploading import: %w", err)
+	}
+
+	// Start ingesting the uploaded file, then poll until it is applied
+	var status D1ImportStatus
+	ingestBody := map[string]string{"action": "ingest", "etag": etag, "filename": initResult.Filename}
+	if err := d.do(ctx, http.MethodPost, urlPath, ingestBody, &status); err != nil {
+		return nil, fmt.Errorf("error ingesting import: %w", err)
+	}
+
+	for {
+		if opts.Progress != nil {
+			opts.Progress(status)
+		}
+
+		switch status.Status {
+		case "complete":
+			return &D1ImportResult{
+				FinalBookmark: status.Result.FinalBookmark,
+				NumQueries:    status.Result.NumQueries,
+				Meta:          status.Result.Meta,
+			}, nil
+		case "error":
+			return nil, fmt.Errorf("import failed: %s", status.Error)
+		}
+
+		if err := sleepContext(ctx, pollInterval(opts.PollInterval)); err != nil {
+			return nil, err
+		}
+
+		pollBody := map[string]string{"action": "poll", "current_bookmark": status.AtBookmark}
+		status = D1ImportStatus{}
+		if err := d.do(ctx, http.MethodPost, urlPath, pollBody, &status); err != nil {
+			return nil, fmt.Errorf("error polling import: %w", err)
+		}
+	}
+}
+
+// download streams the body of a signed URL into w
+func (d *D1Client) download(ctx context.Context, signedURL string, w io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, signedURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := d.transfer.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.Copy(w, resp.Body)
+}
+
+// upload puts a file to a signed upload URL and checks the stored checksum
+func (d *D1Client) upload(ctx context.Context, uploadURL string, body io.Reader, size int64, etag string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.ContentLength = size
+
+	resp, err := d.transfer.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	if got := strings.Trim(resp.Header.Get("ETag"), `"`); got != "" && got != etag {
+		return fmt.Errorf("checksum mismatch after upload: expected %s, got %s", etag, got)
+	}
+
+	return nil
+}
+
+func pollInterval(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return defaultD1PollInterval
+	}
+	return interval
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,184 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// DefaultD1PageSize is the number of rows fetched per request when D1IterateOptions.PageSize is zero
+const DefaultD1PageSize = 1000
+
+// D1IterateOptions controls how Iterate splits a query into pages
+type D1IterateOptions struct {
+	// PageSize is the maximum number of rows fetched per request
+	PageSize int
+
+	// KeyColumns enables keyset pagination on the given result columns, which
+	// must uniquely identify a row. Each page continues after the key of the
+	// previous page's last row instead of using OFFSET, so every page reads the
+	// same number of rows. Without key columns the query is paged with
+	// LIMIT and OFFSET and should have an ORDER BY that gives a stable order.
+	KeyColumns []string
+
+	// Desc orders keyset pages by descending key
+	Desc bool
+}
+
+// D1Iterator reads the rows of a SELECT one page at a time
+type D1Iterator struct {
+	client     *D1Client
+	ctx        context.Context
+	databaseID string
+	query      string
+	params     []interface{}
+	opts       D1IterateOptions
+
+	rowsRead int
+	pages    int
+}
+
+// Iterate returns an iterator over the rows of query that fetches them in pages,
+// so result sets larger than a single D1 response can be read
+// Example usage:
+//
+//	it := d1.Iterate(ctx, databaseID, "SELECT id, name FROM users", nil,
+//	    &cloudflare.D1IterateOptions{KeyColumns: []string{"id"}})
+//	for row, err := range it.All() {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    name, _ := row.Value("name")
+//	    fmt.Println(name)
+//	}
+//	fmt.Println("rows read:", it.RowsRead())
+func (d *D1Client) Iterate(ctx context.Context, databaseID, query string, params []interface{}, opts *D1IterateOptions) *D1Iterator {
+	it := &D1Iterator{
+		client:     d,
+		ctx:        ctx,
+		databaseID: databaseID,
+		query:      strings.TrimRight(strings.TrimSpace(query), ";"),
+		params:     params,
+	}
+	if opts != nil {
+		it.opts = *opts
+	}
+	if it.opts.PageSize <= 0 {
+		it.opts.PageSize = DefaultD1PageSize
+	}
+
+	return it
+}
+
+// RowsRead returns the number of rows read by D1 for every page fetched so far,
+// as reported in each response's meta. This is what D1 bills for.
+func (it *D1Iterator) RowsRead() int {
+	return it.rowsRead
+}
+
+// Pages returns the number of pages fetched so far
+func (it *D1Iterator) Pages() int {
+	return it.pages
+}
+
+// All returns an iterator over every row of the query. Iteration stops after
+// yielding the first error, including the context error once it is cancelled.
+// Each call to All runs the query again from the first page.
+func (it *D1Iterator) All() iter.Seq2[D1RawRow, error] {
+	return func(yield func(D1RawRow, error) bool) {
+		var offset int
+		var after []interface{}
+
+		for {
+			if err := it.ctx.Err(); err != nil {
+				yield(D1RawRow{}, err)
+				return
+			}
+
+			query, params, err := it.pageQuery(offset, after)
+			if err != nil {
+				yield(D1RawRow{}, err)
+				return
+			}
+
+			result, err := it.client.ExecuteRaw(it.ctx, it.databaseID, query, params)
+			if err != nil {
+				yield(D1RawRow{}, fmt.Errorf("error fetching page %d: %w", it.pages+1, err))
+				return
+			}
+			it.pages++
+			it.rowsRead += result.Meta.RowsRead
+
+			rows := result.Results
+			for _, row := range rows.All() {
+				if err := it.ctx.Err(); err != nil {
+					yield(D1RawRow{}, err)
+					return
+				}
+				if !yield(row, nil) {
+					return
+				}
+			}
+
+			if rows.Len() < it.opts.PageSize {
+				return
+			}
+
+			if len(it.opts.KeyColumns) == 0 {
+				offset += rows.Len()
+				continue
+			}
+
+			last := D1RawRow{columns: rows.Columns, values: rows.Rows[rows.Len()-1]}
+			after = make([]interface{}, len(it.opts.KeyColumns))
+			for i, column := range it.opts.KeyColumns {
+				value, ok := last.Value(column)
+				if !ok {
+					yield(D1RawRow{}, fmt.Errorf("key column %q is not in the query results", column))
+					return
+				}
+				after[i] = value
+			}
+		}
+	}
+}
+
+// pageQuery wraps the query in a subquery that selects a single page
+func (it *D1Iterator) pageQuery(offset int, after []interface{}) (string, []interface{}, error) {
+	params := append([]interface{}(nil), it.params...)
+
+	if len(it.opts.KeyColumns) == 0 {
+		query := "SELECT * FROM (" + it.query + ") LIMIT ? OFFSET ?"
+		return query, append(params, it.opts.PageSize, offset), nil
+	}
+
+	keys := make([]string, len(it.opts.KeyColumns))
+	for i, column := range it.opts.KeyColumns {
+		if column == "" {
+			return "", nil, fmt.Errorf("empty key column")
+		}
+		keys[i] = `"` + strings.ReplaceAll(column, `"`, `""`) + `"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT * FROM (" + it.query + ")")
+
+	if after != nil {
+		op, placeholders := ">", strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+		if it.opts.Desc {
+			op = "<"
+		}
+		sb.WriteString(" WHERE (" + strings.Join(keys, ", ") + ") " + op + " (" + placeholders + ")")
+		params = append(params, after...)
+	}
+
+	direction := " ASC"
+	if it.opts.Desc {
+		direction = " DESC"
+	}
+	sb.WriteString(" ORDER BY " + strings.Join(keys, direction+", ") + direction)
+	sb.WriteString(" LIMIT ?")
+
+	return sb.String(), append(params, it.opts.PageSize), nil
+}
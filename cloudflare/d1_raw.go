@@ -0,0 +1,155 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+)
+
+/*
+* https://developers.cloudflare.com/api/resources/d1/subresources/database/methods/raw/
+ */
+
+// D1RawResults holds a result set in columnar form: the column names once,
+// followed by one slice of values per row in the same order. Integers are
+// decoded as int64 so that values above 2^53 keep their precision; other
+// numbers are decoded as float64.
+type D1RawResults struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// UnmarshalJSON decodes the result set, keeping integers exact
+func (r *D1RawResults) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Columns []string        `json:"columns"`
+		Rows    [][]interface{} `json:"rows"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	for _, row := range raw.Rows {
+		for i, value := range row {
+			row[i] = convertNumbers(value)
+		}
+	}
+
+	r.Columns, r.Rows = raw.Columns, raw.Rows
+	return nil
+}
+
+// convertNumbers replaces json.Number values, including the bytes of a blob,
+// with int64 when they are integers and float64 otherwise
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, x := range v {
+			v[i] = convertNumbers(x)
+		}
+	}
+	return v
+}
+
+// D1RawResponseItem is a single statement result from the raw endpoint
+type D1RawResponseItem struct {
+	Meta    D1Meta       `json:"meta"`
+	Results D1RawResults `json:"results"`
+	Success bool         `json:"success"`
+}
+
+// D1RawRow is a single row of a raw result set
+type D1RawRow struct {
+	columns []string
+	values  []interface{}
+}
+
+// Columns returns the column names of the row in result order
+func (r D1RawRow) Columns() []string {
+	return r.columns
+}
+
+// Values returns the values of the row in column order
+func (r D1RawRow) Values() []interface{} {
+	return r.values
+}
+
+// Value returns the value of the first column with the given name
+func (r D1RawRow) Value(column string) (interface{}, bool) {
+	for i, name := range r.columns {
+		if name == column {
+			return r.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// Map returns the row as a column name to value map, like ExecuteQuery results
+func (r D1RawRow) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(r.columns))
+	for i, name := range r.columns {
+		m[name] = r.values[i]
+	}
+	return m
+}
+
+// Len returns the number of rows in the result set
+func (r *D1RawResults) Len() int {
+	return len(r.Rows)
+}
+
+// All returns an iterator over the rows of the result set and their indexes
+// Example usage:
+//
+//	for i, row := range result.Results.All() {
+//	    name, _ := row.Value("name")
+//	    fmt.Println(i, name)
+//	}
+func (r *D1RawResults) All() iter.Seq2[int, D1RawRow] {
+	return func(yield func(int, D1RawRow) bool) {
+		for i, values := range r.Rows {
+			if !yield(i, D1RawRow{columns: r.Columns, values: values}) {
+				return
+			}
+		}
+	}
+}
+
+// ExecuteRaw executes a SQL query using the raw endpoint, which returns
+// rows as arrays instead of objects. This preserves column order and
+// duplicate column names and is cheaper to decode for large result sets.
+func (d *D1Client) ExecuteRaw(ctx context.Context, databaseID, query string, params []interface{}) (_ *D1RawResponseItem, err error) {
+	ctx, op := d.tel.start(ctx, "ExecuteRaw", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	url := fmt.Sprintf("%s/accounts/%s/d1/database/%s/raw",
+		d.config.BaseURL, d.config.AccountID, databaseID)
+
+	stmt := D1Statement{SQL: query, Params: params}
+	if readOnly(stmt) {
+		ctx = WithIdempotent(ctx)
+	}
+
+	var results []D1RawResponseItem
+	if err := d.do(ctx, http.MethodPost, url, stmt, &results); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("empty result set")
+	}
+	op.recordD1(results[0].Meta)
+
+	return &results[0], nil
+}
@@ -0,0 +1,523 @@
+package cloudflare
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoRows is returned by QueryOne when the query does not return any rows
+var ErrNoRows = errors.New("no rows in result set")
+
+// ScanError describes a D1 column that could not be mapped onto a Go value
+type ScanError struct {
+	// Column is the name of the result column
+	Column string
+
+	// Field is the Go struct field the column maps to, if any
+	Field string
+
+	// Err is the underlying cause
+	Err error
+}
+
+func (e *ScanError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("d1 scan: column %q: %v", e.Column, e.Err)
+	}
+	return fmt.Sprintf("d1 scan: column %q into field %s: %v", e.Column, e.Field, e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// QueryInto executes a query and maps every result row onto a value of type T.
+// T is usually a struct whose fields are matched to columns using `d1:"column"`
+// tags; untagged exported fields match a column of the same name, ignoring case.
+// Embedded structs are flattened, pointer fields receive NULL as nil, an
+// embedded struct pointer stays nil if all of its columns are NULL, and
+// time.Time and []byte fields are decoded from SQLite text, numbers and blobs.
+// If T is not a struct, every row must have exactly one column.
+// Example usage:
+//
+//	type User struct {
+//	    ID        int64      `d1:"id"`
+//	    Name      string     `d1:"name"`
+//	    DeletedAt *time.Time `d1:"deleted_at"`
+//	}
+//	users, err := cloudflare.QueryInto[User](ctx, d1Client, databaseID,
+//	    "SELECT id, name, deleted_at FROM users WHERE name = ?", []interface{}{"John"})
+func QueryInto[T any](ctx context.Context, d *D1Client, databaseID, query string, params []interface{}) ([]T, error) {
+	result, err := d.ExecuteRaw(ctx, databaseID, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return ScanRaw[T](&result.Results)
+}
+
+// QueryOne executes a query and maps the first result row onto a value of type T.
+// It returns ErrNoRows if the query produced no rows.
+func QueryOne[T any](ctx context.Context, d *D1Client, databaseID, query string, params []interface{}) (*T, error) {
+	result, err := d.ExecuteRaw(ctx, databaseID, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Results.Rows) == 0 {
+		return nil, ErrNoRows
+	}
+
+	first := D1RawResults{Columns: result.Results.Columns, Rows: result.Results.Rows[:1]}
+	rows, err := ScanRaw[T](&first)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rows[0], nil
+}
+
+// ScanRows maps rows returned by ExecuteQuery onto values of type T
+// using the same rules as QueryInto
+func ScanRows[T any](rows []map[string]interface{}) ([]T, error) {
+	out := make([]T, len(rows))
+	for i, row := range rows {
+		if err := scanMap(row, reflect.ValueOf(&out[i]).Elem()); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+
+	return out, nil
+}
+
+// ScanRaw maps a columnar result set from ExecuteRaw onto values of type T
+// using the same rules as QueryInto. Columns are resolved to fields once
+// for the whole result set rather than once per row.
+func ScanRaw[T any](results *D1RawResults) ([]T, error) {
+	out := make([]T, len(results.Rows))
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	t := reflect.TypeOf(out).Elem()
+	if !isStructDest(t) {
+		if len(results.Columns) != 1 {
+			return nil, fmt.Errorf("d1 scan: expected 1 column for %s, got %d", t, len(results.Columns))
+		}
+		for i, values := range results.Rows {
+			if err := assignValue(reflect.ValueOf(&out[i]).Elem(), values[0]); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i, &ScanError{Column: results.Columns[0], Err: err})
+			}
+		}
+		return out, nil
+	}
+
+	fields := structFields(t)
+	targets := make([]structField, len(results.Columns))
+	for i, column := range results.Columns {
+		field, ok := fields.lookup(column)
+		if !ok {
+			return nil, &ScanError{Column: column, Err: fmt.Errorf("no matching field in %s", t)}
+		}
+		targets[i] = field
+	}
+
+	for i, values := range results.Rows {
+		if len(values) != len(targets) {
+			return nil, fmt.Errorf("row %d: expected %d values, got %d", i, len(targets), len(values))
+		}
+
+		dest := reflect.ValueOf(&out[i]).Elem()
+		for j, value := range values {
+			if err := scanField(dest, targets[j].index, value); err != nil {
+				err = &ScanError{Column: results.Columns[j], Field: targets[j].path, Err: err}
+				return nil, fmt.Errorf("row %d: %w", i, err)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// scanMap assigns the columns of a single row onto dest
+func scanMap(row map[string]interface{}, dest reflect.Value) error {
+	if !isStructDest(dest.Type()) {
+		if len(row) != 1 {
+			return fmt.Errorf("d1 scan: expected 1 column for %s, got %d", dest.Type(), len(row))
+		}
+		for column, value := range row {
+			if err := assignValue(dest, value); err != nil {
+				return &ScanError{Column: column, Err: err}
+			}
+		}
+		return nil
+	}
+
+	fields := structFields(dest.Type())
+	for column, value := range row {
+		if err := scanColumn(fields, dest, column, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanColumn assigns a single column value onto the matching field of dest
+func scanColumn(fields *fieldMap, dest reflect.Value, column string, value interface{}) error {
+	field, ok := fields.lookup(column)
+	if !ok {
+		return &ScanError{Column: column, Err: fmt.Errorf("no matching field in %s", dest.Type())}
+	}
+
+	if err := scanField(dest, field.index, value); err != nil {
+		return &ScanError{Column: column, Field: field.path, Err: err}
+	}
+
+	return nil
+}
+
+// isStructDest reports whether t should be scanned field by field
+func isStructDest(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(scannerType)
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	bytesType   = reflect.TypeOf([]byte(nil))
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// structField locates a column destination within a (possibly nested) struct
+type structField struct {
+	index []int
+	path  string
+}
+
+// fieldMap indexes the fields of a struct type by column name
+type fieldMap struct {
+	exact map[string]structField
+	fold  map[string]structField
+}
+
+func (m *fieldMap) lookup(column string) (structField, bool) {
+	if f, ok := m.exact[column]; ok {
+		return f, true
+	}
+	f, ok := m.fold[strings.ToLower(column)]
+	return f, ok
+}
+
+var fieldCache sync.Map // map[reflect.Type]*fieldMap
+
+// structFields returns the column mapping for struct type t
+func structFields(t reflect.Type) *fieldMap {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(*fieldMap)
+	}
+
+	m := &fieldMap{
+		exact: make(map[string]structField),
+		fold:  make(map[string]structField),
+	}
+	collectFields(m, t, nil, "")
+
+	cached, _ := fieldCache.LoadOrStore(t, m)
+	return cached.(*fieldMap)
+}
+
+// collectFields walks t breadth first so that shallower fields shadow
+// fields of the same name promoted from embedded structs
+func collectFields(m *fieldMap, t reflect.Type, index []int, prefix string) {
+	type embedded struct {
+		t      reflect.Type
+		index  []int
+		prefix string
+	}
+	var nested []embedded
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("d1")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+		path := prefix + sf.Name
+
+		if sf.Anonymous && !hasTag {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				if !sf.IsExported() {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if isStructDest(ft) {
+				nested = append(nested, embedded{ft, fieldIndex, path + "."})
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name != "" {
+			if _, ok := m.exact[name]; !ok {
+				m.exact[name] = structField{fieldIndex, path}
+			}
+			continue
+		}
+
+		key := strings.ToLower(sf.Name)
+		if _, ok := m.fold[key]; !ok {
+			m.fold[key] = structField{fieldIndex, path}
+		}
+	}
+
+	for _, e := range nested {
+		collectFields(m, e.t, e.index, e.prefix)
+	}
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex but allocates
+// nil embedded struct pointers along the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// scanField stores value in the field of dest at index. NULL is not stored
+// through a nil embedded struct pointer, so the pointer stays nil when all of
+// its columns are NULL.
+func scanField(dest reflect.Value, index []int, value interface{}) error {
+	if value == nil {
+		field, err := dest.FieldByIndexErr(index)
+		if err != nil {
+			return nil
+		}
+		return assignValue(field, nil)
+	}
+	return assignValue(fieldByIndexAlloc(dest, index), value)
+}
+
+// assignValue converts a JSON-decoded D1 value and stores it in dst
+func assignValue(dst reflect.Value, src interface{}) error {
+	if dst.CanAddr() {
+		if scanner, ok := dst.Addr().Interface().(sql.Scanner); ok {
+			return scanner.Scan(normalizeScanValue(src))
+		}
+	}
+
+	if src == nil {
+		switch dst.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return fmt.Errorf("cannot store NULL in %s (use a pointer type)", dst.Type())
+	}
+
+	if dst.Kind() == reflect.Pointer {
+		elem := reflect.New(dst.Type().Elem())
+		if err := assignValue(elem.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+
+	switch dst.Type() {
+	case timeType:
+		t, err := parseTime(src)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	case bytesType:
+		b, err := toBytes(src)
+		if err != nil {
+			return err
+		}
+		dst.SetBytes(b)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(src))
+		return nil
+
+	case reflect.String:
+		if s, ok := src.(string); ok {
+			dst.SetString(s)
+			return nil
+		}
+
+	case reflect.Bool:
+		switch s := src.(type) {
+		case bool:
+			dst.SetBool(s)
+			return nil
+		case int64:
+			if s == 0 || s == 1 {
+				dst.SetBool(s == 1)
+				return nil
+			}
+		case float64:
+			if s == 0 || s == 1 {
+				dst.SetBool(s == 1)
+				return nil
+			}
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch s := src.(type) {
+		case int64:
+			if dst.OverflowInt(s) {
+				return fmt.Errorf("value %v overflows %s", s, dst.Type())
+			}
+			dst.SetInt(s)
+			return nil
+		case float64:
+			if s != math.Trunc(s) {
+				return fmt.Errorf("cannot store non-integer %v in %s", s, dst.Type())
+			}
+			// Converting an out of range float is implementation defined,
+			// so check the range before converting
+			if s < math.MinInt64 || s >= math.MaxInt64 || dst.OverflowInt(int64(s)) {
+				return fmt.Errorf("value %v overflows %s", s, dst.Type())
+			}
+			dst.SetInt(int64(s))
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch s := src.(type) {
+		case int64:
+			if s < 0 || dst.OverflowUint(uint64(s)) {
+				return fmt.Errorf("cannot store %v in %s", s, dst.Type())
+			}
+			dst.SetUint(uint64(s))
+			return nil
+		case float64:
+			if s != math.Trunc(s) || s < 0 {
+				return fmt.Errorf("cannot store %v in %s", s, dst.Type())
+			}
+			if s >= math.MaxUint64 || dst.OverflowUint(uint64(s)) {
+				return fmt.Errorf("value %v overflows %s", s, dst.Type())
+			}
+			dst.SetUint(uint64(s))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch s := src.(type) {
+		case int64:
+			dst.SetFloat(float64(s))
+			return nil
+		case float64:
+			dst.SetFloat(s)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot store %T in %s", src, dst.Type())
+}
+
+// sqliteTimeLayouts are the text formats SQLite date functions produce,
+// tried in order when decoding a time.Time
+var sqliteTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseTime decodes SQLite text timestamps and Unix epoch seconds
+func parseTime(src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case string:
+		for _, layout := range sqliteTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as time", v)
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case float64:
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("cannot store %T in time.Time", src)
+}
+
+// toBytes decodes a blob, which D1 returns as a JSON array of byte values
+func toBytes(src interface{}) ([]byte, error) {
+	switch v := src.(type) {
+	case string:
+		return []byte(v), nil
+	case []interface{}:
+		b := make([]byte, len(v))
+		for i, x := range v {
+			var n int64 = -1
+			switch x := x.(type) {
+			case int64:
+				n = x
+			case float64:
+				if x == math.Trunc(x) && x >= 0 && x <= 255 {
+					n = int64(x)
+				}
+			}
+			if n < 0 || n > 255 {
+				return nil, fmt.Errorf("invalid blob byte at offset %d: %v", i, x)
+			}
+			b[i] = byte(n)
+		}
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("cannot store %T in []byte", src)
+}
+
+// normalizeScanValue converts a JSON-decoded value into one of the
+// types database/sql scanners expect
+func normalizeScanValue(src interface{}) interface{} {
+	switch v := src.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case []interface{}:
+		if b, err := toBytes(v); err == nil {
+			return b
+		}
+	}
+	return src
+}
@@ -0,0 +1,63 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestScanRawLargeIntegers(t *testing.T) {
+	var results D1RawResults
+	data := `{"columns": ["id", "score", "data"], "rows": [[9007199254740993, 1.5, [0, 255]]]}`
+	if err := json.Unmarshal([]byte(data), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		ID    int64   `d1:"id"`
+		Score float64 `d1:"score"`
+		Data  []byte  `d1:"data"`
+	}
+	rows, err := ScanRaw[row](&results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rows[0].ID != 9007199254740993 {
+		t.Errorf("ID = %d, want 9007199254740993", rows[0].ID)
+	}
+	if rows[0].Score != 1.5 {
+		t.Errorf("Score = %v, want 1.5", rows[0].Score)
+	}
+	if string(rows[0].Data) != "\x00\xff" {
+		t.Errorf("Data = %v, want [0 255]", rows[0].Data)
+	}
+}
+
+func TestAssignValueRejectsOutOfRangeFloats(t *testing.T) {
+	tests := []struct {
+		name string
+		src  interface{}
+		dst  interface{}
+	}{
+		{"above int64", 1e19, new(int64)},
+		{"below int64", -1e19, new(int64)},
+		{"int64 boundary", math.Exp2(63), new(int64)},
+		{"above int8", 200.0, new(int8)},
+		{"fraction", 1.5, new(int)},
+		{"above uint64", 1e20, new(uint64)},
+		{"negative uint", -1.0, new(uint)},
+		{"negative int64 into uint", int64(-1), new(uint32)},
+		{"int64 into int8", int64(300), new(int8)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := reflect.ValueOf(tt.dst).Elem()
+			if err := assignValue(dst, tt.src); err == nil {
+				t.Errorf("assignValue(%v) stored %v, want error", tt.src, dst.Interface())
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// D1Schema describes the tables of a D1 database
+type D1Schema struct {
+	Tables []D1Table
+}
+
+// D1Table describes a table, its columns, indexes and foreign keys
+type D1Table struct {
+	Name string
+
+	// SQL is the CREATE TABLE statement stored by SQLite
+	SQL string
+
+	Columns     []D1Column
+	Indexes     []D1Index
+	ForeignKeys []D1ForeignKey
+}
+
+// D1Column describes a table column as reported by pragma_table_info
+type D1Column struct {
+	Name    string  `d1:"name"`
+	Type    string  `d1:"type"`
+	NotNull bool    `d1:"notnull"`
+	Default *string `d1:"dflt_value"`
+
+	// PrimaryKey is the 1-based position of the column in the primary key, or 0
+	PrimaryKey int `d1:"pk"`
+}
+
+// D1Index describes an index as reported by pragma_index_list
+type D1Index struct {
+	Name   string
+	Unique bool
+
+	// Origin is "c" for CREATE INDEX, "u" for a UNIQUE constraint and "pk" for a primary key
+	Origin  string
+	Partial bool
+	Columns []string
+}
+
+// D1ForeignKey describes a foreign key as reported by pragma_foreign_key_list
+type D1ForeignKey struct {
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnUpdate   string
+	OnDelete   string
+}
+
+// Table returns the table with the given name
+func (s *D1Schema) Table(name string) (*D1Table, bool) {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i], true
+		}
+	}
+	return nil, false
+}
+
+// Column returns the column with the given name
+func (t *D1Table) Column(name string) (*D1Column, bool) {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i], true
+		}
+	}
+	return nil, false
+}
+
+// PrimaryKey returns the primary key columns in key order
+func (t *D1Table) PrimaryKey() []D1Column {
+	var pk []D1Column
+	for _, c := range t.Columns {
+		if c.PrimaryKey > 0 {
+			pk = append(pk, c)
+		}
+	}
+	sort.Slice(pk, func(i, j int) bool {
+		return pk[i].PrimaryKey < pk[j].PrimaryKey
+	})
+	return pk
+}
+
+// userTables restricts sqlite_master to user tables, skipping SQLite and D1 internals.
+// The underscores are escaped because LIKE treats _ as a wildcard.
+const userTables = `m.type = 'table' AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\' AND m.name NOT LIKE '\_cf\_%' ESCAPE '\'`
+
+// Schema reads the tables, columns, indexes and foreign keys of a D1 database.
+// All catalog queries run in a single batch so they see a consistent snapshot.
+func (d *D1Client) Schema(ctx context.Context, databaseID string) (*D1Schema, error) {
+	results, err := d.Batch(ctx, databaseID, []D1Statement{
+		{SQL: "SELECT m.name, m.sql FROM sqlite_master m WHERE " + userTables + " ORDER BY m.name"},
+		{SQL: `SELECT m.name AS table_name, p.name, p.type, p."notnull", p.dflt_value, p.pk
+FROM sqlite_master m JOIN pragma_table_info(m.name) p
+WHERE ` + userTables + ` ORDER BY m.name, p.cid`},
+		{SQL: `SELECT m.name AS table_name, il.name AS index_name, il."unique", il.origin, il.partial, ii.name AS column_name
+FROM sqlite_master m JOIN pragma_index_list(m.name) il JOIN pragma_index_info(il.name) ii
+WHERE ` + userTables + ` ORDER BY m.name, il.name, ii.seqno`},
+		{SQL: `SELECT m.name AS table_name, fk.id, fk."table" AS ref_table, fk."from", fk."to", fk.on_update, fk.on_delete
+FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) fk
+WHERE ` + userTables + ` ORDER BY m.name, fk.id, fk.seq`},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema: %w", err)
+	}
+
+	type tableRow struct {
+		Name string  `d1:"name"`
+		SQL  *string `d1:"sql"`
+	}
+	type columnRow struct {
+		Table string `d1:"table_name"`
+		D1Column
+	}
+	type indexRow struct {
+		Table   string  `d1:"table_name"`
+		Name    string  `d1:"index_name"`
+		Unique  bool    `d1:"unique"`
+		Origin  string  `d1:"origin"`
+		Partial bool    `d1:"partial"`
+		Column  *string `d1:"column_name"`
+	}
+	type foreignKeyRow struct {
+		Table    string  `d1:"table_name"`
+		ID       int     `d1:"id"`
+		RefTable string  `d1:"ref_table"`
+		From     string  `d1:"from"`
+		To       *string `d1:"to"`
+		OnUpdate string  `d1:"on_update"`
+		OnDelete string  `d1:"on_delete"`
+	}
+
+	tables, err := ScanRows[tableRow](results[0].Results)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tables: %w", err)
+	}
+	columns, err := ScanRows[columnRow](results[1].Results)
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns: %w", err)
+	}
+	indexes, err := ScanRows[indexRow](results[2].Results)
+	if err != nil {
+		return nil, fmt.Errorf("error reading indexes: %w", err)
+	}
+	foreignKeys, err := ScanRows[foreignKeyRow](results[3].Results)
+	if err != nil {
+		return nil, fmt.Errorf("error reading foreign keys: %w", err)
+	}
+
+	schema := &D1Schema{Tables: make([]D1Table, len(tables))}
+	byName := make(map[string]*D1Table, len(tables))
+	for i, t := range tables {
+		schema.Tables[i].Name = t.Name
+		if t.SQL != nil {
+			schema.Tables[i].SQL = *t.SQL
+		}
+		byName[t.Name] = &schema.Tables[i]
+	}
+
+	for _, c := range columns {
+		if t, ok := byName[c.Table]; ok {
+			t.Columns = append(t.Columns, c.D1Column)
+		}
+	}
+
+	for _, ix := range indexes {
+		t, ok := byName[ix.Table]
+		if !ok {
+			continue
+		}
+		if n := len(t.Indexes); n == 0 || t.Indexes[n-1].Name != ix.Name {
+			t.Indexes = append(t.Indexes, D1Index{Name: ix.Name, Unique: ix.Unique, Origin: ix.Origin, Partial: ix.Partial})
+		}
+		// Expression columns have no name
+		column := ""
+		if ix.Column != nil {
+			column = *ix.Column
+		}
+		last := &t.Indexes[len(t.Indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+
+	lastID := make(map[string]int)
+	for _, fk := range foreignKeys {
+		t, ok := byName[fk.Table]
+		if !ok {
+			continue
+		}
+		if id, seen := lastID[fk.Table]; !seen || id != fk.ID {
+			t.ForeignKeys = append(t.ForeignKeys, D1ForeignKey{RefTable: fk.RefTable, OnUpdate: fk.OnUpdate, OnDelete: fk.OnDelete})
+			lastID[fk.Table] = fk.ID
+		}
+		// A nil target column refers to the primary key of the parent table
+		to := ""
+		if fk.To != nil {
+			to = *fk.To
+		}
+		last := &t.ForeignKeys[len(t.ForeignKeys)-1]
+		last.Columns = append(last.Columns, fk.From)
+		last.RefColumns = append(last.RefColumns, to)
+	}
+
+	return schema, nil
+}
+
+// D1SchemaChangeKind identifies the kind of difference between two schemas
+type D1SchemaChangeKind string
+
+// Kinds of schema changes reported by DiffSchemas
+const (
+	D1TableAdded        D1SchemaChangeKind = "table_added"
+	D1TableDropped      D1SchemaChangeKind = "table_dropped"
+	D1ColumnAdded       D1SchemaChangeKind = "column_added"
+	D1ColumnDropped     D1SchemaChangeKind = "column_dropped"
+	D1ColumnChanged     D1SchemaChangeKind = "column_changed"
+	D1IndexAdded        D1SchemaChangeKind = "index_added"
+	D1IndexDropped      D1SchemaChangeKind = "index_dropped"
+	D1IndexChanged      D1SchemaChangeKind = "index_changed"
+	D1ForeignKeyAdded   D1SchemaChangeKind = "foreign_key_added"
+	D1ForeignKeyDropped D1SchemaChangeKind = "foreign_key_dropped"
+)
+
+// D1SchemaChange is a single difference between two schemas
+type D1SchemaChange struct {
+	Kind  D1SchemaChangeKind
+	Table string
+
+	// Name is the column or index affected, or a description of the foreign key
+	Name string
+}
+
+func (c D1SchemaChange) String() string {
+	if c.Name == "" {
+		return fmt.Sprintf("%s %s", c.Kind, c.Table)
+	}
+	return fmt.Sprintf("%s %s.%s", c.Kind, c.Table, c.Name)
+}
+
+// DiffSchemas lists the changes needed to go from one schema to another,
+// ordered by table name
+// Example usage, checking a migration produced the expected schema:
+//
+//	changes := cloudflare.DiffSchemas(expected, actual)
+//	for _, change := range changes {
+//	    log.Printf("schema drift: %s", change)
+//	}
+func DiffSchemas(from, to *D1Schema) []D1SchemaChange {
+	var changes []D1SchemaChange
+
+	names := make(map[string]bool)
+	for _, t := range from.Tables {
+		names[t.Name] = true
+	}
+	for _, t := range to.Tables {
+		names[t.Name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		a, inFrom := from.Table(name)
+		b, inTo := to.Table(name)
+		switch {
+		case !inFrom:
+			changes = append(changes, D1SchemaChange{Kind: D1TableAdded, Table: name})
+		case !inTo:
+			changes = append(changes, D1SchemaChange{Kind: D1TableDropped, Table: name})
+		default:
+			changes = append(changes, diffTables(a, b)...)
+		}
+	}
+
+	return changes
+}
+
+// diffTables compares the columns, indexes and foreign keys of two versions of a table
+func diffTables(a, b *D1Table) []D1SchemaChange {
+	var changes []D1SchemaChange
+
+	for _, c := range a.Columns {
+		other, ok := b.Column(c.Name)
+		if !ok {
+			changes = append(changes, D1SchemaChange{Kind: D1ColumnDropped, Table: a.Name, Name: c.Name})
+		} else if !strings.EqualFold(c.Type, other.Type) || c.NotNull != other.NotNull ||
+			c.PrimaryKey != other.PrimaryKey || !equalDefault(c.Default, other.Default) {
+			changes = append(changes, D1SchemaChange{Kind: D1ColumnChanged, Table: a.Name, Name: c.Name})
+		}
+	}
+	for _, c := range b.Columns {
+		if _, ok := a.Column(c.Name); !ok {
+			changes = append(changes, D1SchemaChange{Kind: D1ColumnAdded, Table: a.Name, Name: c.Name})
+		}
+	}
+
+	indexes := make(map[string]D1Index, len(a.Indexes))
+	for _, ix := range a.Indexes {
+		indexes[ix.Name] = ix
+	}
+	for _, ix := range b.Indexes {
+		old, ok := indexes[ix.Name]
+		delete(indexes, ix.Name)
+		if !ok {
+			changes = append(changes, D1SchemaChange{Kind: D1IndexAdded, Table: a.Name, Name: ix.Name})
+		} else if !reflect.DeepEqual(old, ix) {
+			changes = append(changes, D1SchemaChange{Kind: D1IndexChanged, Table: a.Name, Name: ix.Name})
+		}
+	}
+	for _, ix := range a.Indexes {
+		if _, ok := indexes[ix.Name]; ok {
+			changes = append(changes, D1SchemaChange{Kind: D1IndexDropped, Table: a.Name, Name: ix.Name})
+		}
+	}
+
+	fks := make(map[string]bool, len(a.ForeignKeys))
+	for _, fk := range a.ForeignKeys {
+		fks[fk.String()] = true
+	}
+	for _, fk := range b.ForeignKeys {
+		if !fks[fk.String()] {
+			changes = append(changes, D1SchemaChange{Kind: D1ForeignKeyAdded, Table: a.Name, Name: fk.String()})
+		}
+		delete(fks, fk.String())
+	}
+	for _, fk := range a.ForeignKeys {
+		if fks[fk.String()] {
+			changes = append(changes, D1SchemaChange{Kind: D1ForeignKeyDropped, Table: a.Name, Name: fk.String()})
+		}
+	}
+
+	return changes
+}
+
+// String describes the foreign key, for example "(author_id) -> users(id)"
+func (fk D1ForeignKey) String() string {
+	s := fmt.Sprintf("(%s) -> %s(%s)", strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		s += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		s += " ON DELETE " + fk.OnDelete
+	}
+	return s
+}
+
+func equalDefault(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,221 @@
+package cloudflare_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/cftest"
+)
+
+// newD1 returns a D1 client for a fake server and a database with an accounts table
+func newD1(t *testing.T) (*cloudflare.D1Client, string) {
+	t.Helper()
+
+	srv := cftest.NewServer(t)
+	d1 := srv.Storage().D1
+	databaseID := srv.CreateD1Database("app")
+
+	_, err := d1.ExecuteQuery(context.Background(), databaseID,
+		"CREATE TABLE accounts (id INTEGER PRIMARY KEY, balance INTEGER NOT NULL CHECK (balance >= 0))", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d1, databaseID
+}
+
+func TestBatchReportsFailingStatement(t *testing.T) {
+	ctx := context.Background()
+	d1, databaseID := newD1(t)
+
+	_, err := d1.Batch(ctx, databaseID, []cloudflare.D1Statement{
+		{SQL: "INSERT INTO accounts (id, balance) VALUES (?, ?)", Params: []interface{}{1, 100}},
+		{SQL: "INSERT INTO accounts (id, balance) VALUES (?, ?)", Params: []interface{}{2, 50}},
+		{SQL: "UPDATE accounts SET balance = balance - ? WHERE id = ?", Params: []interface{}{80, 2}},
+		{SQL: "INSERT INTO accounts (id, balance) VALUES (?, ?)", Params: []interface{}{3, 10}},
+	})
+
+	var batchErr *cloudflare.D1BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a *D1BatchError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "CHECK constraint failed") {
+		t.Errorf("error %q does not include the D1 message", err)
+	}
+
+	result, err := d1.ExecuteQuery(ctx, databaseID, "SELECT count(*) AS n FROM accounts", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := result.Results[0]["n"]; n != float64(0) {
+		t.Errorf("%v rows after a failed batch, want 0", n)
+	}
+}
+
+func TestBatchReturnsResultPerStatement(t *testing.T) {
+	d1, databaseID := newD1(t)
+
+	results, err := d1.Batch(context.Background(), databaseID, []cloudflare.D1Statement{
+		{SQL: "INSERT INTO accounts (id, balance) VALUES (1, 1); INSERT INTO accounts (id, balance) VALUES (2, 2)"},
+		{SQL: "SELECT id FROM accounts ORDER BY id"},
+	})
+	if err != nil {
+		t.Fatalf("multi-statement batch entry failed after committing: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want one per SQL statement", len(results))
+	}
+	if len(results[2].Results) != 2 {
+		t.Errorf("SELECT returned %d rows, want 2", len(results[2].Results))
+	}
+}
+
+func TestTxGuard(t *testing.T) {
+	ctx := context.Background()
+	d1, databaseID := newD1(t)
+
+	if _, err := d1.ExecuteQuery(ctx, databaseID, "INSERT INTO accounts (id, balance) VALUES (1, 50), (2, 0)", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	transfer := func(amount int) error {
+		tx := d1.Tx(databaseID)
+		tx.Guard("unused", "1 = 1")
+		tx.Guard("sufficient funds", "(SELECT balance FROM accounts WHERE id = ?) >= ?", 1, amount)
+		tx.Exec("UPDATE accounts SET balance = balance - ? WHERE id = ?", amount, 1)
+		tx.Exec("UPDATE accounts SET balance = balance + ? WHERE id = ?", amount, 2)
+		_, err := tx.Commit(ctx)
+		return err
+	}
+
+	if err := transfer(30); err != nil {
+		t.Fatalf("transfer within balance: %v", err)
+	}
+
+	err := transfer(30)
+	var guardErr *cloudflare.D1GuardError
+	if !errors.As(err, &guardErr) {
+		t.Fatalf("expected a *D1GuardError, got %v", err)
+	}
+	if guardErr.Index != 1 || guardErr.Name != "sufficient funds" {
+		t.Errorf("guard = %d %q, want 1 \"sufficient funds\"", guardErr.Index, guardErr.Name)
+	}
+}
+
+func TestTxErrorOutsideGuard(t *testing.T) {
+	ctx := context.Background()
+	d1, databaseID := newD1(t)
+
+	tx := d1.Tx(databaseID)
+	tx.Guard("always", "1 = 1")
+	tx.Exec("SELECT abs(-9223372036854775808)")
+
+	_, err := tx.Commit(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if errors.Is(err, cloudflare.ErrD1GuardFailed) {
+		t.Errorf("error in a normal statement reported as a guard failure: %v", err)
+	}
+}
+
+// failFirst is middleware that answers the first request with status and a D1 error
+func failFirst(status int, message string, requests *int) cloudflare.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return cloudflare.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*requests++
+			if *requests > 1 {
+				return next.RoundTrip(req)
+			}
+			body := fmt.Sprintf(`{"success":false,"errors":[{"code":7500,"message":%q}],"messages":[]}`, message)
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		})
+	}
+}
+
+func TestTxCommitRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		message string
+		retried bool
+	}{
+		{"busy", http.StatusInternalServerError, "D1_ERROR: database is busy", true},
+		{"rate limited", http.StatusTooManyRequests, "Too many requests", true},
+		{"bad gateway", http.StatusBadGateway, "Bad gateway", false},
+		{"connection lost", http.StatusInternalServerError, "D1_ERROR: Network connection lost.", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv := cftest.NewServer(t)
+			databaseID := srv.CreateD1Database("app")
+
+			requests := 0
+			d1 := srv.Storage(cloudflare.WithMiddleware(failFirst(tt.status, tt.message, &requests))).D1
+
+			tx := d1.Tx(databaseID).WithRetry(3, 0)
+			tx.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)")
+			result, err := tx.Commit(ctx)
+
+			if tt.retried {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Attempts != 2 {
+					t.Errorf("Attempts = %d, want 2", result.Attempts)
+				}
+			} else if err == nil || requests != 1 {
+				t.Errorf("sent %d requests with error %v, want 1 request and an error", requests, err)
+			}
+		})
+	}
+}
+
+func TestDatabaseByNameNotFound(t *testing.T) {
+	d1, _ := newD1(t)
+
+	_, err := d1.DatabaseByName(context.Background(), "missing")
+	if !cloudflare.IsNotFound(err) {
+		t.Errorf("IsNotFound(%v) = false", err)
+	}
+}
+
+func TestSchemaSkipsInternalTables(t *testing.T) {
+	ctx := context.Background()
+	d1, databaseID := newD1(t)
+
+	_, err := d1.ExecuteQuery(ctx, databaseID, `CREATE TABLE _cf_KV (key TEXT);
+CREATE TABLE xcfa (id INTEGER);
+CREATE TABLE acf_log (id INTEGER);
+CREATE TABLE sqlitex (id INTEGER)`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := d1.Schema(ctx, databaseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, table := range schema.Tables {
+		names = append(names, table.Name)
+	}
+	if want := []string{"accounts", "acf_log", "sqlitex", "xcfa"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("tables = %v, want %v", names, want)
+	}
+}
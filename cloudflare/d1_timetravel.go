@@ -0,0 +1,103 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+/*
+* https://developers.cloudflare.com/api/resources/d1/subresources/database/subresources/time_travel/
+ */
+
+// D1Bookmark identifies a point in a D1 database's Time Travel history
+type D1Bookmark struct {
+	Bookmark string `json:"bookmark"`
+}
+
+// D1RestoreResult describes a completed Time Travel restore
+type D1RestoreResult struct {
+	// Bookmark is the point the database was restored to
+	Bookmark string `json:"bookmark"`
+
+	// PreviousBookmark can be used to undo the restore
+	PreviousBookmark string `json:"previous_bookmark"`
+
+	Message string `json:"message"`
+}
+
+// CurrentBookmark returns the Time Travel bookmark for the current state of
+// the database. Capture it before risky changes to have a restore point.
+// Example usage:
+//
+//	bookmark, err := d1Client.CurrentBookmark(ctx, databaseID)
+//	if err != nil {
+//	    log.Fatalf("Failed to get bookmark: %v", err)
+//	}
+//	// ... run migrations ...
+//	_, err = d1Client.RestoreBookmark(ctx, databaseID, bookmark.Bookmark)
+func (d *D1Client) CurrentBookmark(ctx context.Context, databaseID string) (_ *D1Bookmark, err error) {
+	ctx, op := d.tel.start(ctx, "CurrentBookmark", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	return d.bookmark(ctx, databaseID, nil)
+}
+
+// BookmarkAt returns the Time Travel bookmark nearest to the given time
+func (d *D1Client) BookmarkAt(ctx context.Context, databaseID string, at time.Time) (_ *D1Bookmark, err error) {
+	ctx, op := d.tel.start(ctx, "BookmarkAt", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	query := url.Values{}
+	query.Set("timestamp", at.UTC().Format(time.RFC3339))
+	return d.bookmark(ctx, databaseID, query)
+}
+
+func (d *D1Client) bookmark(ctx context.Context, databaseID string, query url.Values) (*D1Bookmark, error) {
+	urlPath := fmt.Sprintf("%s/accounts/%s/d1/database/%s/time_travel/bookmark",
+		d.config.BaseURL, d.config.AccountID, databaseID)
+	if len(query) > 0 {
+		urlPath += "?" + query.Encode()
+	}
+
+	var result D1Bookmark
+	if err := d.do(ctx, http.MethodGet, urlPath, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// RestoreBookmark restores a database to the state at a Time Travel bookmark
+func (d *D1Client) RestoreBookmark(ctx context.Context, databaseID, bookmark string) (_ *D1RestoreResult, err error) {
+	ctx, op := d.tel.start(ctx, "RestoreBookmark", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	query := url.Values{}
+	query.Set("bookmark", bookmark)
+	return d.restore(ctx, databaseID, query)
+}
+
+// RestoreAt restores a database to its state at the given time
+func (d *D1Client) RestoreAt(ctx context.Context, databaseID string, at time.Time) (_ *D1RestoreResult, err error) {
+	ctx, op := d.tel.start(ctx, "RestoreAt", attrD1Database.String(databaseID))
+	defer func() { op.end(err) }()
+
+	query := url.Values{}
+	query.Set("timestamp", at.UTC().Format(time.RFC3339))
+	return d.restore(ctx, databaseID, query)
+}
+
+func (d *D1Client) restore(ctx context.Context, databaseID string, query url.Values) (*D1RestoreResult, error) {
+	urlPath := fmt.Sprintf("%s/accounts/%s/d1/database/%s/time_travel/restore?%s",
+		d.config.BaseURL, d.config.AccountID, databaseID, query.Encode())
+
+	var result D1RestoreResult
+	if err := d.do(ctx, http.MethodPost, urlPath, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,231 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default retry settings for D1Tx.Commit
+const (
+	DefaultD1TxAttempts = 3
+	DefaultD1TxBackoff  = 100 * time.Millisecond
+
+	maxD1TxBackoff = 10 * time.Second
+)
+
+// ErrD1GuardFailed is matched by the error returned from Commit when a guard condition was false
+var ErrD1GuardFailed = errors.New("transaction guard failed")
+
+// D1GuardError reports the guard that aborted a transaction
+type D1GuardError struct {
+	// Index of the guard statement in the transaction
+	Index int
+
+	// Name given to the guard, if known
+	Name string
+}
+
+func (e *D1GuardError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s: statement %d", ErrD1GuardFailed, e.Index)
+	}
+	return fmt.Sprintf("%s: %s", ErrD1GuardFailed, e.Name)
+}
+
+// Is reports whether target is ErrD1GuardFailed
+func (e *D1GuardError) Is(target error) bool {
+	return target == ErrD1GuardFailed
+}
+
+// D1Tx collects statements that are committed together as one atomic batch.
+// D1 has no interactive transactions over HTTP, so reads that decide whether
+// a write may happen are expressed as guards that run inside the batch.
+type D1Tx struct {
+	client     *D1Client
+	databaseID string
+	statements []D1Statement
+
+	// guards maps statement indexes to guard names
+	guards map[int]string
+
+	attempts int
+	backoff  time.Duration
+}
+
+// D1TxResult holds the results of a committed transaction in statement order
+type D1TxResult struct {
+	Results []D1ResponseItem
+
+	// Attempts is the number of times the batch was submitted
+	Attempts int
+}
+
+// Changes returns the number of rows changed by the statement at index i, as returned by Exec
+func (r *D1TxResult) Changes(i int) int {
+	return r.Results[i].Meta.Changes
+}
+
+// TotalChanges returns the number of rows changed by every statement
+func (r *D1TxResult) TotalChanges() int {
+	total := 0
+	for _, result := range r.Results {
+		total += result.Meta.Changes
+	}
+	return total
+}
+
+// Tx starts a transaction builder for databaseID. Nothing is sent until Commit.
+// Example usage:
+//
+//	tx := d1Client.Tx(databaseID)
+//	tx.Guard("sufficient funds", "(SELECT balance FROM accounts WHERE id = ?) >= ?", 1, 100)
+//	debit := tx.Exec("UPDATE accounts SET balance = balance - ? WHERE id = ?", 100, 1)
+//	tx.Exec("UPDATE accounts SET balance = balance + ? WHERE id = ?", 100, 2)
+//
+//	result, err := tx.Commit(ctx)
+//	if errors.Is(err, cloudflare.ErrD1GuardFailed) {
+//	    // nothing was written
+//	}
+//	fmt.Println(result.Changes(debit))
+func (d *D1Client) Tx(databaseID string) *D1Tx {
+	return &D1Tx{
+		client:     d,
+		databaseID: databaseID,
+		guards:     make(map[int]string),
+		attempts:   DefaultD1TxAttempts,
+		backoff:    DefaultD1TxBackoff,
+	}
+}
+
+// Exec adds a statement to the transaction and returns its index in the results
+func (tx *D1Tx) Exec(query string, params ...interface{}) int {
+	tx.statements = append(tx.statements, D1Statement{SQL: query, Params: params})
+	return len(tx.statements) - 1
+}
+
+// Guard adds a condition that must be true when the batch reaches it, or the
+// whole transaction is rolled back and Commit returns a *D1GuardError.
+// The condition is any SQL expression, typically a subquery over current data.
+func (tx *D1Tx) Guard(name, condition string, params ...interface{}) int {
+	// An invalid JSON path raises an error that aborts the batch and quotes the
+	// path, which identifies the guard. D1 reports no per-statement results for
+	// a failed batch, so the error message is all there is to go on.
+	i := len(tx.statements)
+	query := fmt.Sprintf("SELECT CASE WHEN (%s) THEN 1 ELSE json_extract('{}', '%s%d') END", condition, d1GuardMarker, i)
+	tx.Exec(query, params...)
+	tx.guards[i] = name
+	return i
+}
+
+// d1GuardMarker prefixes the guard index in the JSON path of a failing guard
+const d1GuardMarker = "d1tx guard "
+
+var d1GuardPattern = regexp.MustCompile(regexp.QuoteMeta(d1GuardMarker) + `(\d+)`)
+
+// WithRetry sets how many times Commit submits the batch when D1 reports that
+// the database is busy or overloaded, and the initial backoff between attempts,
+// which doubles after each attempt with jitter
+func (tx *D1Tx) WithRetry(attempts int, backoff time.Duration) *D1Tx {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if backoff < 0 {
+		backoff = 0
+	}
+	tx.attempts = attempts
+	tx.backoff = backoff
+	return tx
+}
+
+// Len returns the number of statements in the transaction, including guards
+func (tx *D1Tx) Len() int {
+	return len(tx.statements)
+}
+
+// Commit submits every statement as a single batch. It is retried only on busy,
+// overload and rate limit errors, which D1 raises before running the batch. These
+// retries replace those of the client's RetryPolicy, so the batch is sent at
+// most as many times as set with WithRetry.
+func (tx *D1Tx) Commit(ctx context.Context) (*D1TxResult, error) {
+	if len(tx.statements) == 0 {
+		return nil, fmt.Errorf("transaction is empty")
+	}
+	ctx = withoutRetry(ctx)
+
+	backoff := tx.backoff
+	var err error
+	for attempt := 1; attempt <= tx.attempts; attempt++ {
+		var results []D1ResponseItem
+		results, err = tx.client.Batch(ctx, tx.databaseID, tx.statements)
+		if err == nil {
+			return &D1TxResult{Results: results, Attempts: attempt}, nil
+		}
+
+		if guardErr := tx.guardError(err); guardErr != nil {
+			return nil, guardErr
+		}
+
+		if !isD1Retryable(err) || attempt == tx.attempts {
+			break
+		}
+
+		// Full jitter keeps concurrent writers from retrying in lockstep
+		if err := sleepContext(ctx, time.Duration(rand.Int64N(int64(backoff)+1))); err != nil {
+			return nil, err
+		}
+		backoff = min(backoff*2, maxD1TxBackoff)
+	}
+
+	return nil, fmt.Errorf("error committing transaction: %w", err)
+}
+
+// guardError converts a batch error caused by a guard into a *D1GuardError.
+// The guard is identified by the index quoted in the error message, so an
+// error raised by any other statement is left as it is.
+func (tx *D1Tx) guardError(err error) error {
+	m := d1GuardPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+
+	i, _ := strconv.Atoi(m[1])
+	name, ok := tx.guards[i]
+	if !ok {
+		return nil
+	}
+
+	return &D1GuardError{Index: i, Name: name}
+}
+
+// d1RetryableMessages are fragments of D1 errors raised before the batch ran,
+// which leave the database unchanged and are worth retrying
+var d1RetryableMessages = []string{
+	"overloaded",
+	"busy",
+	"database is locked",
+}
+
+// isD1Retryable reports whether err is a transient D1 error that guarantees the
+// batch was not applied. Gateway errors and lost connections are not retried:
+// the batch may have been committed before the response was lost.
+func isD1Retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range d1RetryableMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package d1driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+
+// errPendingResult is returned by results of statements buffered in a transaction
+var errPendingResult = errors.New("d1driver: result is not available until the transaction is committed")
+
+var (
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.StmtExecContext    = (*stmt)(nil)
+	_ driver.StmtQueryContext   = (*stmt)(nil)
+)
+
+// conn is a logical connection to a D1 database. It holds no network state;
+// every statement is a separate HTTP request.
+type conn struct {
+	client     *cloudflare.D1Client
+	databaseID string
+
+	// tx is the transaction in progress, if any
+	tx *tx
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	c.tx = nil
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("d1driver: transaction already in progress")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("d1driver: read-only transactions are not supported")
+	}
+
+	c.tx = &tx{conn: c, ctx: ctx}
+	return c.tx, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	_, err := c.client.ExecuteQuery(ctx, c.databaseID, "SELECT 1", nil)
+	return err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	params, err := bindParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.tx != nil {
+		c.tx.statements = append(c.tx.statements, cloudflare.D1Statement{SQL: query, Params: params})
+		return pendingResult{}, nil
+	}
+
+	item, err := c.client.ExecuteQuery(ctx, c.databaseID, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return result{meta: item.Meta}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	params, err := bindParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := c.client.ExecuteRaw(ctx, c.databaseID, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rows{columns: item.Results.Columns, values: item.Results.Rows}, nil
+}
+
+// CheckNamedValue rejects named parameters, which D1 does not support
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nv.Name != "" {
+		return fmt.Errorf("d1driver: named parameter %q is not supported, use ? placeholders", nv.Name)
+	}
+
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = value
+	return nil
+}
+
+// bindParams converts driver arguments into D1 positional parameters
+func bindParams(args []driver.NamedValue) ([]interface{}, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	params := make([]interface{}, len(args))
+	for _, arg := range args {
+		if arg.Ordinal < 1 || arg.Ordinal > len(args) {
+			return nil, fmt.Errorf("d1driver: invalid parameter ordinal %d", arg.Ordinal)
+		}
+
+		value := arg.Value
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		params[arg.Ordinal-1] = value
+	}
+
+	return params, nil
+}
+
+// stmt is a prepared statement; D1 has no server-side preparation so it
+// simply remembers the query text
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// tx buffers statements and submits them as a single batch on Commit
+type tx struct {
+	conn       *conn
+	statements []cloudflare.D1Statement
+
+	// ctx is the context given to BeginTx, which bounds the commit
+	ctx context.Context
+}
+
+func (t *tx) Commit() error {
+	defer func() { t.conn.tx = nil }()
+
+	if len(t.statements) == 0 {
+		return nil
+	}
+
+	_, err := t.conn.client.Batch(t.ctx, t.conn.databaseID, t.statements)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	t.conn.tx = nil
+	return nil
+}
+
+// result exposes the statement metadata returned by D1
+type result struct {
+	meta cloudflare.D1Meta
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return int64(r.meta.LastRowID), nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return int64(r.meta.Changes), nil
+}
+
+// pendingResult is returned for statements buffered in a transaction
+type pendingResult struct{}
+
+func (pendingResult) LastInsertId() (int64, error) {
+	return 0, errPendingResult
+}
+
+func (pendingResult) RowsAffected() (int64, error) {
+	return 0, errPendingResult
+}
+
+// rows iterates over a columnar result set from the raw endpoint
+type rows struct {
+	columns []string
+	values  [][]interface{}
+	pos     int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	r.values = nil
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+
+	row := r.values[r.pos]
+	r.pos++
+
+	for i := range dest {
+		value, err := driverValue(row[i])
+		if err != nil {
+			return fmt.Errorf("d1driver: column %s: %w", r.columns[i], err)
+		}
+		dest[i] = value
+	}
+
+	return nil
+}
+
+// driverValue converts a JSON-decoded D1 value into a driver.Value.
+// Blobs arrive as arrays of byte values.
+func driverValue(v interface{}) (driver.Value, error) {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+		return v, nil
+	case []interface{}:
+		b := make([]byte, len(v))
+		for i, x := range v {
+			var n int64
+			switch x := x.(type) {
+			case int64:
+				n = x
+			case float64:
+				if x != math.Trunc(x) || x < 0 || x > math.MaxUint8 {
+					return nil, fmt.Errorf("invalid blob byte at offset %d: %v", i, x)
+				}
+				n = int64(x)
+			default:
+				return nil, fmt.Errorf("invalid blob byte at offset %d: %v", i, x)
+			}
+			if n < 0 || n > math.MaxUint8 {
+				return nil, fmt.Errorf("invalid blob byte at offset %d: %v", i, x)
+			}
+			b[i] = byte(n)
+		}
+		return b, nil
+	}
+	return v, nil
+}
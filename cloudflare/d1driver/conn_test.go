@@ -0,0 +1,48 @@
+package d1driver_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/cftest"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/d1driver"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	srv := cftest.NewServer(t)
+	storage := srv.Storage()
+	db := sql.OpenDB(d1driver.NewConnector(storage.D1, srv.CreateD1Database("app")))
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestBlobRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE files (id INTEGER PRIMARY KEY, data BLOB)"); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{0, 1, 0x7f, 0x80, 0xff, '"'}
+	if _, err := db.ExecContext(ctx, "INSERT INTO files (id, data) VALUES (?, ?)", 1, data); err != nil {
+		t.Fatal(err)
+	}
+
+	var typ string
+	var got []byte
+	if err := db.QueryRowContext(ctx, "SELECT typeof(data), data FROM files WHERE id = ?", 1).Scan(&typ, &got); err != nil {
+		t.Fatal(err)
+	}
+	if typ != "blob" {
+		t.Errorf("stored as %s, want blob", typ)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("data = %v, want %v", got, data)
+	}
+}
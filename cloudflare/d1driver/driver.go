@@ -0,0 +1,124 @@
+// Package d1driver provides a database/sql driver for Cloudflare D1 built on
+// top of the D1 HTTP API.
+//
+// Importing the package registers the driver under the name "d1":
+//
+//	import _ "github.com/BLANK-13/go-cloud-utils/cloudflare/d1driver"
+//
+//	db, err := sql.Open("d1", "account-id/database-id?token=api-token")
+//
+// The data source name has the form
+//
+//	<account-id>/<database-id>?token=<api-token>[&base_url=<url>][&timeout=<duration>]
+//
+// D1 has no interactive transactions. Statements executed inside a
+// transaction are buffered and submitted as a single atomic batch on Commit,
+// so their results are only available after Commit returns. Queries inside
+// a transaction run immediately and do not see the buffered writes.
+package d1driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+
+func init() {
+	sql.Register("d1", &Driver{})
+}
+
+// Driver implements driver.Driver and driver.DriverContext for D1
+type Driver struct{}
+
+// Open returns a new connection to the database described by dsn
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(context.Background())
+}
+
+// OpenConnector parses dsn and returns a connector for it
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	config, databaseID, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &connector{
+		client:     cloudflare.NewD1Client(config),
+		databaseID: databaseID,
+		driver:     d,
+	}, nil
+}
+
+// ParseDSN parses a data source name into a Cloudflare configuration and database ID
+func ParseDSN(dsn string) (*cloudflare.CloudflareConfig, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid d1 dsn: %w", err)
+	}
+
+	accountID, databaseID, ok := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	if !ok || accountID == "" || databaseID == "" {
+		return nil, "", errors.New("invalid d1 dsn: expected <account-id>/<database-id>")
+	}
+
+	query := u.Query()
+	token := query.Get("token")
+	if token == "" {
+		return nil, "", errors.New("invalid d1 dsn: missing token parameter")
+	}
+
+	config := cloudflare.NewConfig(token, accountID)
+
+	if baseURL := query.Get("base_url"); baseURL != "" {
+		config.BaseURL = baseURL
+	}
+
+	if timeout := query.Get("timeout"); timeout != "" {
+		config.Timeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid d1 dsn: timeout: %w", err)
+		}
+	}
+
+	return config, databaseID, nil
+}
+
+// NewConnector returns a connector that uses an existing D1 client,
+// for use with sql.OpenDB
+// Example usage:
+//
+//	db := sql.OpenDB(d1driver.NewConnector(storage.D1, databaseID))
+func NewConnector(client *cloudflare.D1Client, databaseID string) driver.Connector {
+	return &connector{
+		client:     client,
+		databaseID: databaseID,
+		driver:     &Driver{},
+	}
+}
+
+// connector creates connections sharing a single D1 client
+type connector struct {
+	client     *cloudflare.D1Client
+	databaseID string
+	driver     *Driver
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{client: c.client, databaseID: c.databaseID}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
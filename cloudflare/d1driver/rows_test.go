@@ -0,0 +1,37 @@
+package d1driver
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestRowsNextRejectsInvalidBlobs(t *testing.T) {
+	tests := []struct {
+		name string
+		blob []interface{}
+	}{
+		{"out of range", []interface{}{float64(1), float64(256)}},
+		{"negative", []interface{}{int64(-1)}},
+		{"fraction", []interface{}{1.5}},
+		{"not a number", []interface{}{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &rows{columns: []string{"data"}, values: [][]interface{}{{tt.blob}}}
+			if err := r.Next(make([]driver.Value, 1)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	r := &rows{columns: []string{"data"}, values: [][]interface{}{{[]interface{}{float64(0), int64(255)}}}}
+	dest := make([]driver.Value, 1)
+	if err := r.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := dest[0].([]byte); !ok || !bytes.Equal(b, []byte{0, 255}) {
+		t.Errorf("got %v, want [0 255]", dest[0])
+	}
+}
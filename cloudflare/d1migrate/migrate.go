@@ -0,0 +1,438 @@
+// Package d1migrate applies versioned SQL migrations to Cloudflare D1 databases.
+//
+// Migrations are read from an fs.FS, which makes them easy to embed:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	files, _ := fs.Sub(migrations, "migrations")
+//	m, err := d1migrate.New(storage.D1, databaseID, files)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	result, err := m.Up(ctx)
+//
+// Each migration is a file named <version>_<name>.up.sql, with an optional
+// <version>_<name>.down.sql used to roll it back. A file named
+// <version>_<name>.sql is treated as an up migration. Versions are positive
+// integers and are applied in ascending order.
+//
+// Applied versions are recorded with a checksum of their up script in a
+// _migrations table. If a migration file changes after it has been applied
+// the migrator refuses to run and returns a *ChecksumError.
+//
+// With WithRestorePoint the migrator captures a D1 Time Travel bookmark
+// before it changes anything, so a failed or unwanted run can be undone with
+// D1Client.RestoreBookmark.
+package d1migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/internal/sqlsplit"
+)
+
+// DefaultTable is the name of the table that records applied migrations
+const DefaultTable = "_migrations"
+
+// ErrChecksumMismatch is matched by errors.Is for any *ChecksumError
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// ChecksumError reports an applied migration whose file has since changed
+type ChecksumError struct {
+	Version int64
+	Name    string
+	Applied string
+	Current string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("migration %d_%s was modified after it was applied (applied checksum %s, current %s)",
+		e.Version, e.Name, e.Applied, e.Current)
+}
+
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// AppliedMigration is a row of the migrations table
+type AppliedMigration struct {
+	Version   int64  `d1:"version"`
+	Name      string `d1:"name"`
+	Checksum  string `d1:"checksum"`
+	AppliedAt string `d1:"applied_at"`
+}
+
+// Result describes the migrations an Up or Down call applied or, in
+// dry-run mode, would have applied. If a call fails once it has planned its
+// migrations, it still returns a Result listing those applied before the
+// failure, which may be none.
+type Result struct {
+	Migrations []Migration
+	DryRun     bool
+
+	// Bookmark is the Time Travel bookmark captured before any migration ran
+	// when the Migrator was created with WithRestorePoint. Pass it to
+	// D1Client.RestoreBookmark to undo the run.
+	Bookmark string
+}
+
+// Migrator applies migrations to a single D1 database
+type Migrator struct {
+	client     *cloudflare.D1Client
+	databaseID string
+	migrations []Migration
+	table      string
+	dryRun     bool
+	restore    bool
+}
+
+// Option configures a Migrator
+type Option func(*Migrator)
+
+// WithTable overrides the name of the migrations table
+func WithTable(name string) Option {
+	return func(m *Migrator) {
+		m.table = name
+	}
+}
+
+// WithDryRun makes Up and Down report what they would do without executing anything
+func WithDryRun() Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// WithRestorePoint captures a Time Travel bookmark before Up or Down changes
+// anything and reports it in Result.Bookmark
+func WithRestorePoint() Option {
+	return func(m *Migrator) {
+		m.restore = true
+	}
+}
+
+// New loads the migrations in fsys and returns a Migrator for the database
+func New(client *cloudflare.D1Client, databaseID string, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		client:     client,
+		databaseID: databaseID,
+		migrations: migrations,
+		table:      DefaultTable,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+?)(\.up|\.down)?\.sql$`)
+
+// Load reads the migration files at the root of fsys, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, mig.Name, match[2])
+		}
+
+		if match[3] == ".down" {
+			mig.Down = string(data)
+			continue
+		}
+		if mig.Up != "" {
+			return nil, fmt.Errorf("migration version %d has more than one up script", version)
+		}
+		mig.Up = string(data)
+		mig.Checksum = checksum(data)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrations returns the migrations loaded from the file system
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Applied returns the migrations recorded in the database, ordered by version
+func (m *Migrator) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	return cloudflare.QueryInto[AppliedMigration](ctx, m.client, m.databaseID,
+		fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s ORDER BY version", quoteIdent(m.table)), nil)
+}
+
+// Pending returns the migrations that have not been applied yet.
+// It fails with a *ChecksumError if an applied migration has changed.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+
+	return pending, nil
+}
+
+// Up applies every pending migration in version order
+func (m *Migrator) Up(ctx context.Context) (*Result, error) {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo applies pending migrations up to and including version.
+// A version of 0 applies all pending migrations.
+// Each migration runs in its own batch together with its bookkeeping row,
+// so a failing migration leaves the database at the previous version.
+func (m *Migrator) UpTo(ctx context.Context, version int64) (*Result, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{DryRun: m.dryRun}
+	for _, mig := range pending {
+		if version > 0 && mig.Version > version {
+			break
+		}
+		result.Migrations = append(result.Migrations, mig)
+	}
+
+	if m.dryRun || len(result.Migrations) == 0 {
+		return result, nil
+	}
+
+	if err := m.captureBookmark(ctx, result); err != nil {
+		result.Migrations = nil
+		return result, err
+	}
+
+	if err := m.ensureTable(ctx); err != nil {
+		result.Migrations = nil
+		return result, err
+	}
+
+	for i, mig := range result.Migrations {
+		_, err := m.client.Batch(ctx, m.databaseID, scriptBatch(mig.Up, cloudflare.D1Statement{
+			SQL:    fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)", quoteIdent(m.table)),
+			Params: []interface{}{mig.Version, mig.Name, mig.Checksum, time.Now().UTC().Format(time.RFC3339)},
+		}))
+		if err != nil {
+			result.Migrations = result.Migrations[:i]
+			return result, fmt.Errorf("error applying migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// Down rolls back the most recently applied migrations, newest first.
+// steps is the number of migrations to roll back.
+func (m *Migrator) Down(ctx context.Context, steps int) (*Result, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be positive")
+	}
+
+	applied, err := m.verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{DryRun: m.dryRun}
+	for i := len(m.migrations) - 1; i >= 0 && len(result.Migrations) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+		}
+		result.Migrations = append(result.Migrations, mig)
+	}
+
+	if m.dryRun || len(result.Migrations) == 0 {
+		return result, nil
+	}
+
+	if err := m.captureBookmark(ctx, result); err != nil {
+		result.Migrations = nil
+		return result, err
+	}
+
+	for i, mig := range result.Migrations {
+		_, err := m.client.Batch(ctx, m.databaseID, scriptBatch(mig.Down, cloudflare.D1Statement{
+			SQL:    fmt.Sprintf("DELETE FROM %s WHERE version = ?", quoteIdent(m.table)),
+			Params: []interface{}{mig.Version},
+		}))
+		if err != nil {
+			result.Migrations = result.Migrations[:i]
+			return result, fmt.Errorf("error rolling back migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// scriptBatch splits a migration script into one batch entry per statement,
+// so that D1 returns a result for each entry, and appends the bookkeeping
+// statement that records the change in the same transaction
+func scriptBatch(script string, bookkeeping cloudflare.D1Statement) []cloudflare.D1Statement {
+	var statements []cloudflare.D1Statement
+	for _, sql := range sqlsplit.Split(script) {
+		statements = append(statements, cloudflare.D1Statement{SQL: sql})
+	}
+	return append(statements, bookkeeping)
+}
+
+// verify compares applied checksums with the loaded files and returns
+// the applied migrations keyed by version
+func (m *Migrator) verify(ctx context.Context) (map[int64]AppliedMigration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]AppliedMigration, len(applied))
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+
+	for _, mig := range m.migrations {
+		a, ok := byVersion[mig.Version]
+		if ok && a.Checksum != mig.Checksum {
+			return nil, &ChecksumError{
+				Version: mig.Version,
+				Name:    mig.Name,
+				Applied: a.Checksum,
+				Current: mig.Checksum,
+			}
+		}
+	}
+
+	return byVersion, nil
+}
+
+// captureBookmark records the current Time Travel bookmark in result
+// if the migrator was configured to do so
+func (m *Migrator) captureBookmark(ctx context.Context, result *Result) error {
+	if !m.restore {
+		return nil
+	}
+
+	bookmark, err := m.client.CurrentBookmark(ctx, m.databaseID)
+	if err != nil {
+		return fmt.Errorf("error capturing restore point: %w", err)
+	}
+	result.Bookmark = bookmark.Bookmark
+
+	return nil
+}
+
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	result, err := m.client.ExecuteQuery(ctx, m.databaseID,
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", []interface{}{m.table})
+	if err != nil {
+		return false, err
+	}
+
+	return len(result.Results) > 0, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.client.ExecuteQuery(ctx, m.databaseID, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+)`, quoteIdent(m.table)), nil)
+	if err != nil {
+		return fmt.Errorf("error creating migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// quoteIdent quotes an SQLite identifier
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,214 @@
+package d1migrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/cftest"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/d1migrate"
+)
+
+var migrations = fstest.MapFS{
+	"1_users.up.sql": {Data: []byte(`
+CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL);
+CREATE UNIQUE INDEX users_email ON users (email);
+-- Seed an admin
+INSERT INTO users (email) VALUES ('admin@example.com');
+`)},
+	"1_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"2_audit.up.sql": {Data: []byte(`
+CREATE TABLE audit (id INTEGER PRIMARY KEY, user_id INTEGER, action TEXT);
+-- Record every new user; admins separately
+CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN
+    INSERT INTO audit (user_id, action)
+    VALUES (new.id, CASE WHEN new.email LIKE 'admin@%' THEN 'admin; by trigger' ELSE 'created' END);
+    UPDATE audit SET action = action || '.' WHERE user_id = new.id;
+END;
+`)},
+	"2_audit.down.sql": {Data: []byte("DROP TRIGGER users_audit; DROP TABLE audit;")},
+}
+
+func newMigrator(t *testing.T, fsys fstest.MapFS) (*cloudflare.D1Client, string, *d1migrate.Migrator) {
+	t.Helper()
+
+	srv := cftest.NewServer(t)
+	d1 := srv.Storage().D1
+	databaseID := srv.CreateD1Database("app")
+
+	m, err := d1migrate.New(d1, databaseID, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d1, databaseID, m
+}
+
+func TestUpAppliesMultiStatementMigrations(t *testing.T) {
+	ctx := context.Background()
+	d1, databaseID, m := newMigrator(t, migrations)
+
+	result, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Migrations) != 2 {
+		t.Fatalf("applied %d migrations, want 2", len(result.Migrations))
+	}
+
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("recorded %d migrations, want 2", len(applied))
+	}
+
+	// A second run has nothing to do and must not re-apply the seed
+	result, err = m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Migrations) != 0 {
+		t.Errorf("second run applied %d migrations, want 0", len(result.Migrations))
+	}
+
+	if _, err := d1.ExecuteQuery(ctx, databaseID, "INSERT INTO users (email) VALUES ('a@example.com')", nil); err != nil {
+		t.Fatal(err)
+	}
+	actions, err := cloudflare.QueryInto[string](ctx, d1, databaseID, "SELECT action FROM audit", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0] != "created." {
+		t.Errorf("trigger wrote %q, want [created.]", actions)
+	}
+}
+
+func TestUpFailureLeavesPreviousVersion(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"1_users.up.sql": migrations["1_users.up.sql"],
+		"2_broken.up.sql": {Data: []byte(`
+CREATE TABLE broken (id INTEGER PRIMARY KEY);
+INSERT INTO missing_table VALUES (1);
+`)},
+	}
+	d1, databaseID, m := newMigrator(t, fsys)
+
+	result, err := m.Up(ctx)
+	if err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	var batchErr *cloudflare.D1BatchError
+	if !errors.As(err, &batchErr) {
+		t.Errorf("expected a *D1BatchError, got %v", err)
+	}
+	if len(result.Migrations) != 1 {
+		t.Errorf("result lists %d applied migrations, want 1", len(result.Migrations))
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Version != 2 {
+		t.Errorf("pending = %v, want version 2", pending)
+	}
+
+	tables, err := cloudflare.QueryInto[string](ctx, d1, databaseID,
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'broken'", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 0 {
+		t.Error("failed migration left its table behind")
+	}
+}
+
+func TestDown(t *testing.T) {
+	ctx := context.Background()
+	_, _, m := newMigrator(t, migrations)
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := m.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Migrations) != 1 || result.Migrations[0].Version != 2 {
+		t.Fatalf("rolled back %v, want version 2", result.Migrations)
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Version != 2 {
+		t.Errorf("pending = %v, want version 2", pending)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	srv := cftest.NewServer(t)
+	d1 := srv.Storage().D1
+	databaseID := srv.CreateD1Database("app")
+
+	m, err := d1migrate.New(d1, databaseID, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := fstest.MapFS{"1_users.up.sql": {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")}}
+	m, err = d1migrate.New(d1, databaseID, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, d1migrate.ErrChecksumMismatch) {
+		t.Errorf("got %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestUpFailureBeforeMigrating(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("restore point", func(t *testing.T) {
+		// The fake server has no Time Travel endpoints, so capturing a bookmark fails
+		srv := cftest.NewServer(t)
+		m, err := d1migrate.New(srv.Storage().D1, srv.CreateD1Database("app"), migrations, d1migrate.WithRestorePoint())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := m.Up(ctx)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if result == nil || len(result.Migrations) != 0 {
+			t.Errorf("result = %+v, want a result without migrations", result)
+		}
+	})
+
+	t.Run("migrations table", func(t *testing.T) {
+		d1, databaseID, m := newMigrator(t, migrations)
+		if _, err := d1.ExecuteQuery(ctx, databaseID, "CREATE TABLE other (id INTEGER); CREATE INDEX _migrations ON other (id)", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := m.Up(ctx)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if result == nil || len(result.Migrations) != 0 {
+			t.Errorf("result = %+v, want a result without migrations", result)
+		}
+	})
+}
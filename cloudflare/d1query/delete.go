@@ -0,0 +1,50 @@
+package d1query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DeleteBuilder builds a DELETE statement
+type DeleteBuilder struct {
+	table     string
+	where     whereClause
+	returning returningClause
+}
+
+// Delete starts a DELETE statement on table
+func Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+// Where adds a condition; multiple conditions are joined with AND
+func (b *DeleteBuilder) Where(cond string, args ...interface{}) *DeleteBuilder {
+	b.where.add(cond, args)
+	return b
+}
+
+// Returning returns the given columns of the deleted rows; use "*" for all columns
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.returning.columns = columns
+	return b
+}
+
+// Build returns the SQL text and its positional parameters
+func (b *DeleteBuilder) Build() (string, []interface{}, error) {
+	if b.table == "" {
+		return "", nil, errors.New("delete: missing table")
+	}
+
+	var sb strings.Builder
+	var params []interface{}
+
+	sb.WriteString("DELETE FROM " + QuoteIdent(b.table))
+	if err := b.where.build(&sb, &params); err != nil {
+		return "", nil, fmt.Errorf("delete: %w", err)
+	}
+
+	b.returning.build(&sb)
+
+	return sb.String(), params, nil
+}
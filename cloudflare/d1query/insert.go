@@ -0,0 +1,127 @@
+package d1query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// InsertBuilder builds an INSERT statement, optionally with an upsert clause
+type InsertBuilder struct {
+	table     string
+	columns   []string
+	rows      [][]interface{}
+	conflict  []string
+	onUpdate  []string
+	doNothing bool
+	returning returningClause
+}
+
+// Insert starts an INSERT statement into table
+func Insert(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+// Columns sets the columns that Values provides values for
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = columns
+	return b
+}
+
+// Values adds a row; call it repeatedly to insert several rows
+func (b *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// Set adds a column and its value to a single-row insert
+func (b *InsertBuilder) Set(column string, value interface{}) *InsertBuilder {
+	b.columns = append(b.columns, column)
+	if len(b.rows) == 0 {
+		b.rows = append(b.rows, nil)
+	}
+	b.rows[0] = append(b.rows[0], value)
+	return b
+}
+
+// OnConflict starts an upsert clause for a conflict on the given columns.
+// Follow it with DoUpdate or DoNothing.
+func (b *InsertBuilder) OnConflict(columns ...string) *InsertBuilder {
+	b.conflict = columns
+	return b
+}
+
+// DoUpdate overwrites the given columns with the values that would have
+// been inserted when the insert conflicts
+func (b *InsertBuilder) DoUpdate(columns ...string) *InsertBuilder {
+	b.onUpdate = columns
+	return b
+}
+
+// DoNothing skips rows that conflict
+func (b *InsertBuilder) DoNothing() *InsertBuilder {
+	b.doNothing = true
+	return b
+}
+
+// Returning returns the given columns of the inserted rows; use "*" for all columns
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning.columns = columns
+	return b
+}
+
+// Build returns the SQL text and its positional parameters
+func (b *InsertBuilder) Build() (string, []interface{}, error) {
+	if b.table == "" {
+		return "", nil, errors.New("insert: missing table")
+	}
+	if len(b.columns) == 0 || len(b.rows) == 0 {
+		return "", nil, errors.New("insert: no values")
+	}
+	if b.doNothing && len(b.onUpdate) > 0 {
+		return "", nil, errors.New("insert: DoNothing and DoUpdate are mutually exclusive")
+	}
+	if len(b.conflict) > 0 && !b.doNothing && len(b.onUpdate) == 0 {
+		return "", nil, errors.New("insert: OnConflict requires DoUpdate or DoNothing")
+	}
+
+	var sb strings.Builder
+	var params []interface{}
+
+	sb.WriteString("INSERT INTO " + QuoteIdent(b.table) + " (" + quoteIdents(b.columns) + ") VALUES ")
+
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(b.columns)), ", ") + ")"
+	for i, row := range b.rows {
+		if len(row) != len(b.columns) {
+			return "", nil, fmt.Errorf("insert: row %d has %d values for %d columns", i, len(row), len(b.columns))
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(placeholders)
+		params = append(params, row...)
+	}
+
+	if b.doNothing || len(b.onUpdate) > 0 {
+		sb.WriteString(" ON CONFLICT")
+		if len(b.conflict) > 0 {
+			sb.WriteString(" (" + quoteIdents(b.conflict) + ")")
+		} else if len(b.onUpdate) > 0 {
+			return "", nil, errors.New("insert: DoUpdate requires OnConflict columns")
+		}
+
+		if b.doNothing {
+			sb.WriteString(" DO NOTHING")
+		} else {
+			sets := make([]string, len(b.onUpdate))
+			for i, column := range b.onUpdate {
+				sets[i] = QuoteIdent(column) + " = excluded." + QuoteIdent(column)
+			}
+			sb.WriteString(" DO UPDATE SET " + strings.Join(sets, ", "))
+		}
+	}
+
+	b.returning.build(&sb)
+
+	return sb.String(), params, nil
+}
@@ -0,0 +1,231 @@
+// Package d1query builds SQLite statements for Cloudflare D1 with positional
+// parameters, so values never need to be formatted into SQL strings.
+//
+//	q := d1query.Select("id", "name").
+//	    From("users").
+//	    Where("email = ?", email).
+//	    Where("role IN ?", []string{"admin", "owner"}).
+//	    OrderByDesc("created_at").
+//	    Limit(10)
+//
+//	users, err := d1query.All[User](ctx, storage.D1, databaseID, q)
+//
+// Table and column names passed to From, Insert, Update, Delete, Set, Columns,
+// OrderBy, OnConflict and Returning are quoted as identifiers. The column expressions
+// given to Select and the condition strings given to Where are emitted as
+// written and must not contain untrusted input; pass values as arguments.
+package d1query
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+
+// Builder is implemented by every statement builder in this package
+type Builder interface {
+	// Build returns the SQL text and its positional parameters
+	Build() (string, []interface{}, error)
+}
+
+// Statement builds b into a statement that can be passed to D1Client.Batch
+func Statement(b Builder) (cloudflare.D1Statement, error) {
+	query, params, err := b.Build()
+	if err != nil {
+		return cloudflare.D1Statement{}, err
+	}
+
+	return cloudflare.D1Statement{SQL: query, Params: params}, nil
+}
+
+// Exec builds b and executes it with client
+func Exec(ctx context.Context, client *cloudflare.D1Client, databaseID string, b Builder) (*cloudflare.D1ResponseItem, error) {
+	query, params, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ExecuteQuery(ctx, databaseID, query, params)
+}
+
+// All builds b, executes it and scans every row into a T using cloudflare.QueryInto
+func All[T any](ctx context.Context, client *cloudflare.D1Client, databaseID string, b Builder) ([]T, error) {
+	query, params, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudflare.QueryInto[T](ctx, client, databaseID, query, params)
+}
+
+// One builds b, executes it and scans the first row into a T using cloudflare.QueryOne
+func One[T any](ctx context.Context, client *cloudflare.D1Client, databaseID string, b Builder) (*T, error) {
+	query, params, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudflare.QueryOne[T](ctx, client, databaseID, query, params)
+}
+
+// Batch builds every builder and executes them atomically with D1Client.Batch
+func Batch(ctx context.Context, client *cloudflare.D1Client, databaseID string, builders ...Builder) ([]cloudflare.D1ResponseItem, error) {
+	statements := make([]cloudflare.D1Statement, len(builders))
+	for i, b := range builders {
+		stmt, err := Statement(b)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i, err)
+		}
+		statements[i] = stmt
+	}
+
+	return client.Batch(ctx, databaseID, statements)
+}
+
+// QuoteIdent quotes an SQLite identifier such as a table or column name
+func QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// condition is a single WHERE clause fragment
+type condition struct {
+	sql  string
+	args []interface{}
+}
+
+// whereClause collects conditions that are joined with AND
+type whereClause struct {
+	conditions []condition
+}
+
+func (w *whereClause) add(sql string, args []interface{}) {
+	w.conditions = append(w.conditions, condition{sql: sql, args: args})
+}
+
+// build appends the WHERE clause, expanding slice arguments into
+// parenthesized placeholder lists so that "id IN ?" works with a slice
+func (w *whereClause) build(sb *strings.Builder, params *[]interface{}) error {
+	if len(w.conditions) == 0 {
+		return nil
+	}
+
+	sb.WriteString(" WHERE ")
+	for i, c := range w.conditions {
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+
+		expanded, args, err := expandPlaceholders(c.sql, c.args)
+		if err != nil {
+			return err
+		}
+
+		if len(w.conditions) > 1 {
+			sb.WriteString("(" + expanded + ")")
+		} else {
+			sb.WriteString(expanded)
+		}
+		*params = append(*params, args...)
+	}
+
+	return nil
+}
+
+// expandPlaceholders matches each ? in sql with an argument, replacing the
+// placeholder of a slice argument with one placeholder per element
+func expandPlaceholders(sql string, args []interface{}) (string, []interface{}, error) {
+	var sb strings.Builder
+	var out []interface{}
+	var quote byte
+	n := 0
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+
+		// Placeholders inside string literals and quoted identifiers are literal text
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			sb.WriteByte(c)
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+			sb.WriteByte(c)
+			continue
+		case c != '?':
+			sb.WriteByte(c)
+			continue
+		}
+
+		if n >= len(args) {
+			return "", nil, fmt.Errorf("not enough arguments for %q", sql)
+		}
+		arg := args[n]
+		n++
+
+		if !isList(arg) {
+			sb.WriteByte('?')
+			out = append(out, arg)
+			continue
+		}
+
+		v := reflect.ValueOf(arg)
+		if v.Len() == 0 {
+			return "", nil, fmt.Errorf("empty list argument in %q", sql)
+		}
+		sb.WriteByte('(')
+		for j := 0; j < v.Len(); j++ {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteByte('?')
+			out = append(out, v.Index(j).Interface())
+		}
+		sb.WriteByte(')')
+	}
+
+	if n != len(args) {
+		return "", nil, fmt.Errorf("too many arguments for %q: expected %d, got %d", sql, n, len(args))
+	}
+
+	return sb.String(), out, nil
+}
+
+// isList reports whether arg is a slice or array that expands into a list of
+// placeholders. Byte slices are blobs and bind to a single placeholder.
+func isList(arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	v := reflect.ValueOf(arg)
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+// returningClause appends RETURNING to INSERT, UPDATE and DELETE statements
+type returningClause struct {
+	columns []string
+}
+
+func (r *returningClause) build(sb *strings.Builder) {
+	if len(r.columns) == 0 {
+		return
+	}
+	if len(r.columns) == 1 && r.columns[0] == "*" {
+		sb.WriteString(" RETURNING *")
+		return
+	}
+	sb.WriteString(" RETURNING " + quoteIdents(r.columns))
+}
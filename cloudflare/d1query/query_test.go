@@ -0,0 +1,86 @@
+package d1query
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/cftest"
+)
+
+func TestSelectExpandsListArguments(t *testing.T) {
+	sql, params, err := Select("id").From("users").Where("id IN ?", []int{1, 2, 3}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `SELECT id FROM "users" WHERE id IN (?, ?, ?)`; sql != want {
+		t.Errorf("sql = %s, want %s", sql, want)
+	}
+	if want := []interface{}{1, 2, 3}; !reflect.DeepEqual(params, want) {
+		t.Errorf("params = %v, want %v", params, want)
+	}
+}
+
+func TestUpdateSetRejectsLists(t *testing.T) {
+	_, _, err := Update("users").Set("tags", []string{"a", "b"}).Where("id = ?", 1).Build()
+	if err == nil {
+		t.Fatal("expected an error for a list value")
+	}
+
+	sql, params, err := Update("files").Set("data", []byte{1, 2}).Where("id = ?", 1).Build()
+	if err != nil {
+		t.Fatalf("blob value: %v", err)
+	}
+	if want := `UPDATE "files" SET "data" = ? WHERE id = ?`; sql != want {
+		t.Errorf("sql = %s, want %s", sql, want)
+	}
+	if len(params) != 2 {
+		t.Errorf("params = %v, want 2 values", params)
+	}
+}
+
+func TestInsertOnConflictRequiresAction(t *testing.T) {
+	_, _, err := Insert("users").Set("id", 1).OnConflict("id").Build()
+	if err == nil {
+		t.Fatal("expected an error for OnConflict without DoUpdate or DoNothing")
+	}
+
+	sql, _, err := Insert("users").Set("id", 1).Set("name", "Ada").OnConflict("id").DoUpdate("name").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "users" ("id", "name") VALUES (?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name"`
+	if sql != want {
+		t.Errorf("sql = %s, want %s", sql, want)
+	}
+}
+
+func TestBlobRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	srv := cftest.NewServer(t)
+	d1 := srv.Storage().D1
+	databaseID := srv.CreateD1Database("app")
+
+	if _, err := d1.ExecuteQuery(ctx, databaseID, "CREATE TABLE files (id INTEGER PRIMARY KEY, data BLOB)", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{0, 1, 0xff}
+	if _, err := Exec(ctx, d1, databaseID, Insert("files").Set("id", 1).Set("data", data)); err != nil {
+		t.Fatal(err)
+	}
+
+	type file struct {
+		Type string `d1:"type"`
+		Data []byte `d1:"data"`
+	}
+	got, err := One[file](ctx, d1, databaseID, Select("typeof(data) AS type", "data").From("files").Where("id = ?", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "blob" || !bytes.Equal(got.Data, data) {
+		t.Errorf("stored %s %v, want blob %v", got.Type, got.Data, data)
+	}
+}
@@ -0,0 +1,99 @@
+package d1query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SelectBuilder builds a SELECT statement
+type SelectBuilder struct {
+	columns []string
+	table   string
+	where   whereClause
+	orderBy []string
+	limit   *int
+	offset  *int
+}
+
+// Select starts a SELECT statement. With no columns it selects *.
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+// From sets the table to select from
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.table = table
+	return b
+}
+
+// Where adds a condition; multiple conditions are joined with AND.
+// A slice argument expands its placeholder into a list, as in "id IN ?".
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	b.where.add(cond, args)
+	return b
+}
+
+// OrderBy sorts the results by column in ascending order
+func (b *SelectBuilder) OrderBy(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, QuoteIdent(column)+" ASC")
+	return b
+}
+
+// OrderByDesc sorts the results by column in descending order
+func (b *SelectBuilder) OrderByDesc(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, QuoteIdent(column)+" DESC")
+	return b
+}
+
+// Limit caps the number of rows returned
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = &n
+	return b
+}
+
+// Offset skips the first n rows
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = &n
+	return b
+}
+
+// Build returns the SQL text and its positional parameters
+func (b *SelectBuilder) Build() (string, []interface{}, error) {
+	if b.table == "" {
+		return "", nil, errors.New("select: missing table")
+	}
+
+	var sb strings.Builder
+	var params []interface{}
+
+	sb.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		sb.WriteString("*")
+	} else {
+		sb.WriteString(strings.Join(b.columns, ", "))
+	}
+	sb.WriteString(" FROM " + QuoteIdent(b.table))
+
+	if err := b.where.build(&sb, &params); err != nil {
+		return "", nil, fmt.Errorf("select: %w", err)
+	}
+
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+
+	if b.limit != nil {
+		sb.WriteString(" LIMIT ?")
+		params = append(params, *b.limit)
+	} else if b.offset != nil {
+		// SQLite only accepts OFFSET after a LIMIT
+		sb.WriteString(" LIMIT -1")
+	}
+	if b.offset != nil {
+		sb.WriteString(" OFFSET ?")
+		params = append(params, *b.offset)
+	}
+
+	return sb.String(), params, nil
+}
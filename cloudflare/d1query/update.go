@@ -0,0 +1,89 @@
+package d1query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UpdateBuilder builds an UPDATE statement
+type UpdateBuilder struct {
+	table     string
+	sets      []condition
+	where     whereClause
+	returning returningClause
+
+	// err is the first invalid Set, reported by Build
+	err error
+}
+
+// Update starts an UPDATE statement on table
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// Set assigns value to column. The value cannot be a list; Build returns an
+// error if it is.
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	if isList(value) && b.err == nil {
+		b.err = fmt.Errorf("update: cannot set column %s to a list", column)
+	}
+	b.sets = append(b.sets, condition{sql: QuoteIdent(column) + " = ?", args: []interface{}{value}})
+	return b
+}
+
+// SetExpr assigns an SQL expression to column, for example
+// SetExpr("version", "version + 1")
+func (b *UpdateBuilder) SetExpr(column, expr string, args ...interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, condition{sql: QuoteIdent(column) + " = " + expr, args: args})
+	return b
+}
+
+// Where adds a condition; multiple conditions are joined with AND
+func (b *UpdateBuilder) Where(cond string, args ...interface{}) *UpdateBuilder {
+	b.where.add(cond, args)
+	return b
+}
+
+// Returning returns the given columns of the updated rows; use "*" for all columns
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.returning.columns = columns
+	return b
+}
+
+// Build returns the SQL text and its positional parameters
+func (b *UpdateBuilder) Build() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if b.table == "" {
+		return "", nil, errors.New("update: missing table")
+	}
+	if len(b.sets) == 0 {
+		return "", nil, errors.New("update: no columns to set")
+	}
+
+	var sb strings.Builder
+	var params []interface{}
+
+	sb.WriteString("UPDATE " + QuoteIdent(b.table) + " SET ")
+	for i, set := range b.sets {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		expanded, args, err := expandPlaceholders(set.sql, set.args)
+		if err != nil {
+			return "", nil, fmt.Errorf("update: %w", err)
+		}
+		sb.WriteString(expanded)
+		params = append(params, args...)
+	}
+
+	if err := b.where.build(&sb, &params); err != nil {
+		return "", nil, fmt.Errorf("update: %w", err)
+	}
+
+	b.returning.build(&sb)
+
+	return sb.String(), params, nil
+}
@@ -0,0 +1,224 @@
+package d1repo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// model describes how a struct type maps onto a table
+type model struct {
+	table   string
+	columns []column
+
+	// keys are the primary key columns in declaration order
+	keys []int
+
+	// version is the index of the optimistic locking column, or -1
+	version int
+
+	// softDelete is the index of the soft delete timestamp column, or -1
+	softDelete int
+}
+
+// column is a struct field mapped onto a table column
+type column struct {
+	name  string
+	index []int
+	kind  reflect.Kind
+}
+
+// tabler is implemented by types that name their own table
+type tabler interface {
+	TableName() string
+}
+
+// newModel reads the d1 tags of t. Supported options after the column name are
+// pk, version and softdelete; a blank field tagged `d1:"name,table"` names the table.
+func newModel(t reflect.Type) (*model, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("d1repo: %s is not a struct", t)
+	}
+
+	m := &model{version: -1, softDelete: -1}
+	if err := m.collect(t, nil); err != nil {
+		return nil, err
+	}
+
+	if tn, ok := reflect.New(t).Interface().(tabler); ok {
+		m.table = tn.TableName()
+	}
+	if m.table == "" {
+		m.table = snakeCase(t.Name()) + "s"
+	}
+
+	if len(m.keys) == 0 {
+		return nil, fmt.Errorf("d1repo: %s has no primary key; tag a field with `d1:\"column,pk\"`", t)
+	}
+
+	return m, nil
+}
+
+func (m *model) collect(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("d1")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if sf.Name == "_" {
+			if hasOption(opts, "table") {
+				m.table = name
+			}
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if sf.Anonymous && tag == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := m.collect(ft, fieldIndex); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		m.columns = append(m.columns, column{name: name, index: fieldIndex, kind: sf.Type.Kind()})
+		n := len(m.columns) - 1
+
+		if hasOption(opts, "pk") {
+			m.keys = append(m.keys, n)
+		}
+		if hasOption(opts, "version") {
+			if m.version >= 0 {
+				return fmt.Errorf("d1repo: %s has more than one version column", t)
+			}
+			switch sf.Type.Kind() {
+			case reflect.Int, reflect.Int32, reflect.Int64:
+			default:
+				return fmt.Errorf("d1repo: version column %s must be an integer", name)
+			}
+			m.version = n
+		}
+		if hasOption(opts, "softdelete") {
+			if m.softDelete >= 0 {
+				return fmt.Errorf("d1repo: %s has more than one softdelete column", t)
+			}
+			m.softDelete = n
+		}
+	}
+
+	return nil
+}
+
+func hasOption(opts, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isKey reports whether column i is part of the primary key
+func (m *model) isKey(i int) bool {
+	for _, k := range m.keys {
+		if k == i {
+			return true
+		}
+	}
+	return false
+}
+
+// autoIncrement reports whether the table has a single integer primary key
+// that SQLite assigns when it is left out of an INSERT
+func (m *model) autoIncrement() bool {
+	if len(m.keys) != 1 {
+		return false
+	}
+	switch m.columns[m.keys[0]].kind {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func (m *model) columnNames(indexes []int) []string {
+	names := make([]string, len(indexes))
+	for i, idx := range indexes {
+		names[i] = m.columns[idx].name
+	}
+	return names
+}
+
+// field returns the struct field of column i in v, allocating nil embedded
+// struct pointers on the way so that the field can be set
+func (m *model) field(v reflect.Value, i int) reflect.Value {
+	for j, x := range m.columns[i].index {
+		if j > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// value returns the value of column i in v to bind as a parameter. A column
+// promoted through a nil embedded struct pointer is NULL.
+func (m *model) value(v reflect.Value, i int) interface{} {
+	f, err := v.FieldByIndexErr(m.columns[i].index)
+	if err != nil {
+		return nil
+	}
+	return f.Interface()
+}
+
+// isZero reports whether column i of v is its zero value or NULL
+func (m *model) isZero(v reflect.Value, i int) bool {
+	f, err := v.FieldByIndexErr(m.columns[i].index)
+	return err != nil || f.IsZero()
+}
+
+// keyValues returns the primary key values of v
+func (m *model) keyValues(v reflect.Value) []interface{} {
+	values := make([]interface{}, len(m.keys))
+	for i, k := range m.keys {
+		values[i] = m.value(v, k)
+	}
+	return values
+}
+
+// snakeCase converts a Go name such as "UserProfile" to "user_profile"
+func snakeCase(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,440 @@
+// Package d1repo provides a generic repository over a single Cloudflare D1 table.
+//
+// The table layout is read from the d1 struct tags of the model type:
+//
+//	type User struct {
+//	    ID        int64      `d1:"id,pk"`
+//	    Email     string     `d1:"email"`
+//	    Version   int64      `d1:"version,version"`
+//	    DeletedAt *time.Time `d1:"deleted_at,softdelete"`
+//	}
+//
+//	users, err := d1repo.New[User](storage.D1, databaseID)
+//	err = users.Insert(ctx, &User{Email: "a@example.com"})
+//	user, err := users.Get(ctx, 1)
+//
+// The table name is taken from the WithTable option, a TableName method on the
+// model, a blank field tagged `d1:"users,table"`, or else the snake_case plural
+// of the type name, in that order.
+//
+// A column tagged version is checked and incremented by Update, which returns
+// an ErrConflict error when the row was changed concurrently. A column tagged
+// softdelete makes Delete set a timestamp instead of removing the row, and rows
+// with a timestamp are hidden from Get and List.
+package d1repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/d1query"
+)
+
+// ErrNotFound is matched by errors returned when a row does not exist
+var ErrNotFound = errors.New("d1repo: row not found")
+
+// ErrConflict is matched by errors returned when an update loses an optimistic concurrency check
+var ErrConflict = errors.New("d1repo: version conflict")
+
+// NotFoundError reports the table and key of a missing row
+type NotFoundError struct {
+	Table string
+	Key   []interface{}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("d1repo: no row in %s with key %v", e.Table, e.Key)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ConflictError reports a row whose version no longer matches the version being updated
+type ConflictError struct {
+	Table   string
+	Key     []interface{}
+	Version int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("d1repo: row in %s with key %v was modified since version %d", e.Table, e.Key, e.Version)
+}
+
+// Is reports whether target is ErrConflict
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Option configures a Repository
+type Option func(*options)
+
+type options struct {
+	table string
+}
+
+// WithTable overrides the table name derived from the model type
+func WithTable(name string) Option {
+	return func(o *options) {
+		o.table = name
+	}
+}
+
+// Repository reads and writes values of type T in a single D1 table
+type Repository[T any] struct {
+	client     *cloudflare.D1Client
+	databaseID string
+	model      *model
+}
+
+// New creates a repository for T, reading the table layout from its d1 tags
+func New[T any](client *cloudflare.D1Client, databaseID string, opts ...Option) (*Repository[T], error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	m, err := newModel(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	if o.table != "" {
+		m.table = o.table
+	}
+
+	return &Repository[T]{
+		client:     client,
+		databaseID: databaseID,
+		model:      m,
+	}, nil
+}
+
+// Table returns the name of the table the repository reads and writes
+func (r *Repository[T]) Table() string {
+	return r.model.table
+}
+
+// Get returns the row with the given primary key values, in declaration order.
+// It returns a NotFoundError if there is no such row or it has been soft deleted.
+func (r *Repository[T]) Get(ctx context.Context, key ...interface{}) (*T, error) {
+	if len(key) != len(r.model.keys) {
+		return nil, fmt.Errorf("d1repo: %s has %d key columns, got %d values", r.model.table, len(r.model.keys), len(key))
+	}
+
+	q := r.selectQuery()
+	q.Where(r.keyCondition(), key...)
+	if live := r.liveCondition(); live != "" {
+		q.Where(live)
+	}
+
+	v, err := d1query.One[T](ctx, r.client, r.databaseID, q)
+	if errors.Is(err, cloudflare.ErrNoRows) {
+		return nil, &NotFoundError{Table: r.model.table, Key: key}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting row from %s: %w", r.model.table, err)
+	}
+
+	return v, nil
+}
+
+// Insert adds v as a new row. A zero integer primary key is left for SQLite to
+// assign and set on v afterwards; a zero version column is stored as 1. v is
+// only changed once the insert succeeded.
+func (r *Repository[T]) Insert(ctx context.Context, v *T) error {
+	rv := reflect.ValueOf(v).Elem()
+	m := r.model
+
+	auto := m.autoIncrement() && m.isZero(rv, m.keys[0])
+	initVersion := m.version >= 0 && m.isZero(rv, m.version)
+
+	q := d1query.Insert(m.table)
+	for i, c := range m.columns {
+		if auto && i == m.keys[0] {
+			continue
+		}
+		if initVersion && i == m.version {
+			q.Set(c.name, 1)
+			continue
+		}
+		q.Set(c.name, m.value(rv, i))
+	}
+
+	resp, err := d1query.Exec(ctx, r.client, r.databaseID, q)
+	if err != nil {
+		return fmt.Errorf("error inserting row into %s: %w", m.table, err)
+	}
+
+	if auto {
+		m.field(rv, m.keys[0]).SetInt(int64(resp.Meta.LastRowID))
+	}
+	if initVersion {
+		m.field(rv, m.version).SetInt(1)
+	}
+
+	return nil
+}
+
+// Update writes every non-key column of v to the live row with the same primary key.
+// With a version column the update only applies if the stored version equals the
+// version of v, which is incremented on success; otherwise a ConflictError is returned.
+func (r *Repository[T]) Update(ctx context.Context, v *T) error {
+	rv := reflect.ValueOf(v).Elem()
+	m := r.model
+	key := m.keyValues(rv)
+
+	q := d1query.Update(m.table)
+	for i, c := range m.columns {
+		if m.isKey(i) || i == m.version || i == m.softDelete {
+			continue
+		}
+		q.Set(c.name, m.value(rv, i))
+	}
+	q.Where(r.keyCondition(), key...)
+	if live := r.liveCondition(); live != "" {
+		q.Where(live)
+	}
+
+	var version int64
+	if m.version >= 0 {
+		name := m.columns[m.version].name
+		version = m.field(rv, m.version).Int()
+		q.SetExpr(name, d1query.QuoteIdent(name)+" + 1")
+		q.Where(d1query.QuoteIdent(name)+" = ?", version)
+	}
+
+	resp, err := d1query.Exec(ctx, r.client, r.databaseID, q)
+	if err != nil {
+		return fmt.Errorf("error updating row in %s: %w", m.table, err)
+	}
+
+	if resp.Meta.Changes == 0 {
+		if m.version < 0 {
+			return &NotFoundError{Table: m.table, Key: key}
+		}
+
+		exists, err := r.exists(ctx, key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return &NotFoundError{Table: m.table, Key: key}
+		}
+		return &ConflictError{Table: m.table, Key: key, Version: version}
+	}
+
+	if m.version >= 0 {
+		m.field(rv, m.version).SetInt(version + 1)
+	}
+
+	return nil
+}
+
+// Delete removes the row with the given primary key values. With a softdelete
+// column the row is kept and its timestamp set instead. It returns a
+// NotFoundError if there is no such row or it was already soft deleted.
+func (r *Repository[T]) Delete(ctx context.Context, key ...interface{}) error {
+	m := r.model
+	if len(key) != len(m.keys) {
+		return fmt.Errorf("d1repo: %s has %d key columns, got %d values", m.table, len(m.keys), len(key))
+	}
+
+	var q d1query.Builder
+	if m.softDelete >= 0 {
+		u := d1query.Update(m.table).Set(m.columns[m.softDelete].name, time.Now().UTC())
+		if m.version >= 0 {
+			name := d1query.QuoteIdent(m.columns[m.version].name)
+			u.SetExpr(m.columns[m.version].name, name+" + 1")
+		}
+		u.Where(r.keyCondition(), key...)
+		if live := r.liveCondition(); live != "" {
+			u.Where(live)
+		}
+		q = u
+	} else {
+		d := d1query.Delete(m.table)
+		d.Where(r.keyCondition(), key...)
+		q = d
+	}
+
+	resp, err := d1query.Exec(ctx, r.client, r.databaseID, q)
+	if err != nil {
+		return fmt.Errorf("error deleting row from %s: %w", m.table, err)
+	}
+	if resp.Meta.Changes == 0 {
+		return &NotFoundError{Table: m.table, Key: key}
+	}
+
+	return nil
+}
+
+// ListOptions filters and paginates List
+type ListOptions struct {
+	// Where is an optional SQL condition with ? placeholders for Args
+	Where string
+	Args  []interface{}
+
+	// Limit is the maximum number of rows per page; zero returns every row
+	Limit int
+
+	// After is the primary key of the last row of the previous page, taken from Page.Next
+	After []interface{}
+
+	// Desc orders rows by descending primary key
+	Desc bool
+
+	// IncludeDeleted also returns soft deleted rows
+	IncludeDeleted bool
+}
+
+// Page is one page of List results
+type Page[T any] struct {
+	Items []T
+
+	// Next is the primary key to pass as ListOptions.After for the following page,
+	// or nil when there are no more rows
+	Next []interface{}
+}
+
+// List returns rows ordered by primary key, paging with keyset pagination so
+// that each page costs the same number of rows read regardless of its position
+func (r *Repository[T]) List(ctx context.Context, opts ListOptions) (*Page[T], error) {
+	m := r.model
+
+	q := r.selectQuery()
+	if opts.Where != "" {
+		q.Where(opts.Where, opts.Args...)
+	}
+	if !opts.IncludeDeleted {
+		if live := r.liveCondition(); live != "" {
+			q.Where(live)
+		}
+	}
+
+	if opts.After != nil {
+		if len(opts.After) != len(m.keys) {
+			return nil, fmt.Errorf("d1repo: %s has %d key columns, got %d cursor values", m.table, len(m.keys), len(opts.After))
+		}
+		op := ">"
+		if opts.Desc {
+			op = "<"
+		}
+		q.Where(r.keyTuple()+" "+op+" "+placeholderTuple(len(m.keys)), opts.After...)
+	}
+
+	for _, name := range m.columnNames(m.keys) {
+		if opts.Desc {
+			q.OrderByDesc(name)
+		} else {
+			q.OrderBy(name)
+		}
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	if opts.Limit > 0 {
+		q.Limit(opts.Limit + 1)
+	}
+
+	items, err := d1query.All[T](ctx, r.client, r.databaseID, q)
+	if err != nil {
+		return nil, fmt.Errorf("error listing rows from %s: %w", m.table, err)
+	}
+
+	page := &Page[T]{Items: items}
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		page.Items = items[:opts.Limit]
+		page.Next = m.keyValues(reflect.ValueOf(&page.Items[opts.Limit-1]).Elem())
+	}
+
+	return page, nil
+}
+
+// Restore clears the softdelete timestamp of a soft deleted row
+func (r *Repository[T]) Restore(ctx context.Context, key ...interface{}) error {
+	m := r.model
+	if m.softDelete < 0 {
+		return fmt.Errorf("d1repo: %s has no softdelete column", m.table)
+	}
+	if len(key) != len(m.keys) {
+		return fmt.Errorf("d1repo: %s has %d key columns, got %d values", m.table, len(m.keys), len(key))
+	}
+
+	name := m.columns[m.softDelete].name
+	q := d1query.Update(m.table).Set(name, nil)
+	q.Where(r.keyCondition(), key...)
+	q.Where(d1query.QuoteIdent(name) + " IS NOT NULL")
+
+	resp, err := d1query.Exec(ctx, r.client, r.databaseID, q)
+	if err != nil {
+		return fmt.Errorf("error restoring row in %s: %w", m.table, err)
+	}
+	if resp.Meta.Changes == 0 {
+		return &NotFoundError{Table: m.table, Key: key}
+	}
+
+	return nil
+}
+
+func (r *Repository[T]) selectQuery() *d1query.SelectBuilder {
+	columns := make([]string, len(r.model.columns))
+	for i, c := range r.model.columns {
+		columns[i] = d1query.QuoteIdent(c.name)
+	}
+	return d1query.Select(columns...).From(r.model.table)
+}
+
+// keyCondition returns a condition matching the row with the given primary key
+func (r *Repository[T]) keyCondition() string {
+	names := r.model.columnNames(r.model.keys)
+	for i, name := range names {
+		names[i] = d1query.QuoteIdent(name) + " = ?"
+	}
+	return strings.Join(names, " AND ")
+}
+
+// liveCondition returns a condition excluding soft deleted rows, or "" without a softdelete column
+func (r *Repository[T]) liveCondition() string {
+	if r.model.softDelete < 0 {
+		return ""
+	}
+	return d1query.QuoteIdent(r.model.columns[r.model.softDelete].name) + " IS NULL"
+}
+
+func (r *Repository[T]) exists(ctx context.Context, key []interface{}) (bool, error) {
+	q := d1query.Select("1").From(r.model.table)
+	q.Where(r.keyCondition(), key...)
+	if live := r.liveCondition(); live != "" {
+		q.Where(live)
+	}
+
+	rows, err := d1query.All[int64](ctx, r.client, r.databaseID, q)
+	if err != nil {
+		return false, fmt.Errorf("error checking row in %s: %w", r.model.table, err)
+	}
+
+	return len(rows) > 0, nil
+}
+
+func (r *Repository[T]) keyTuple() string {
+	names := r.model.columnNames(r.model.keys)
+	for i, name := range names {
+		names[i] = d1query.QuoteIdent(name)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func placeholderTuple(n int) string {
+	if n == 1 {
+		return "?"
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
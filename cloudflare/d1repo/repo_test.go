@@ -0,0 +1,143 @@
+package d1repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/cftest"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/d1repo"
+)
+
+type Audit struct {
+	CreatedBy string `d1:"created_by"`
+}
+
+type User struct {
+	*Audit
+	ID        int64      `d1:"id,pk"`
+	Email     string     `d1:"email"`
+	Version   int64      `d1:"version,version"`
+	DeletedAt *time.Time `d1:"deleted_at,softdelete"`
+}
+
+func newUsers(t *testing.T) *d1repo.Repository[User] {
+	t.Helper()
+
+	srv := cftest.NewServer(t)
+	d1 := srv.Storage().D1
+	databaseID := srv.CreateD1Database("app")
+
+	_, err := d1.ExecuteQuery(context.Background(), databaseID, `CREATE TABLE users (
+		id INTEGER PRIMARY KEY,
+		email TEXT NOT NULL UNIQUE,
+		created_by TEXT,
+		version INTEGER NOT NULL,
+		deleted_at TEXT
+	)`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := d1repo.New[User](d1, databaseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return users
+}
+
+func TestInsertAndGet(t *testing.T) {
+	ctx := context.Background()
+	users := newUsers(t)
+
+	u := &User{Audit: &Audit{CreatedBy: "admin"}, Email: "a@example.com"}
+	if err := users.Insert(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID == 0 || u.Version != 1 {
+		t.Errorf("after insert ID = %d, Version = %d; want an assigned ID and version 1", u.ID, u.Version)
+	}
+
+	// A nil embedded pointer is stored as NULL and read back as nil
+	noAudit := &User{Email: "b@example.com"}
+	if err := users.Insert(ctx, noAudit); err != nil {
+		t.Fatal(err)
+	}
+	got, err := users.Get(ctx, noAudit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Audit != nil {
+		t.Errorf("Audit = %+v, want nil", got.Audit)
+	}
+
+	page, err := users.List(ctx, d1repo.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("listed %d rows, want 2", len(page.Items))
+	}
+
+	got, err = users.Get(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != u.Email || got.Audit == nil || got.CreatedBy != "admin" {
+		t.Errorf("Get = %+v, want %+v", got, u)
+	}
+
+	if _, err := users.Get(ctx, 999); !errors.Is(err, d1repo.ErrNotFound) {
+		t.Errorf("Get missing row: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestInsertFailureLeavesValueUnchanged(t *testing.T) {
+	ctx := context.Background()
+	users := newUsers(t)
+
+	if err := users.Insert(ctx, &User{Email: "a@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dup := &User{Email: "a@example.com"}
+	if err := users.Insert(ctx, dup); err == nil {
+		t.Fatal("expected a unique constraint error")
+	}
+	if dup.ID != 0 || dup.Version != 0 {
+		t.Errorf("after a failed insert ID = %d, Version = %d; want both 0", dup.ID, dup.Version)
+	}
+}
+
+func TestUpdateConflict(t *testing.T) {
+	ctx := context.Background()
+	users := newUsers(t)
+
+	u := &User{Email: "a@example.com"}
+	if err := users.Insert(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := *u
+	u.Email = "b@example.com"
+	if err := users.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Version != 2 {
+		t.Errorf("Version = %d after update, want 2", u.Version)
+	}
+
+	stale.Email = "c@example.com"
+	if err := users.Update(ctx, &stale); !errors.Is(err, d1repo.ErrConflict) {
+		t.Errorf("stale update: got %v, want ErrConflict", err)
+	}
+
+	if err := users.Delete(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Get(ctx, u.ID); !errors.Is(err, d1repo.ErrNotFound) {
+		t.Errorf("Get soft deleted row: got %v, want ErrNotFound", err)
+	}
+}
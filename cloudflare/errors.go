@@ -0,0 +1,181 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrorDetail is a single entry of the errors or messages array of a Cloudflare API response
+type ErrorDetail struct {
+	Code             int    `json:"code"`
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url,omitempty"`
+	Source           struct {
+		Pointer string `json:"pointer,omitempty"`
+	} `json:"source"`
+}
+
+// D1Error represents an error in a D1 response
+type D1Error = ErrorDetail
+
+// APIError is returned by every client when the Cloudflare API responds with a
+// non-2xx status or with success set to false.
+// Example usage:
+//
+//	var apiErr *cloudflare.APIError
+//	if errors.As(err, &apiErr) {
+//	    log.Printf("status %d, ray %s: %v", apiErr.StatusCode, apiErr.RayID, apiErr.Errors)
+//	}
+type APIError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+
+	// Errors and Messages are decoded from the response envelope when present
+	Errors   []ErrorDetail
+	Messages []ErrorDetail
+
+	// RayID is the cf-ray header, which Cloudflare support uses to find a request
+	RayID string
+
+	// Body holds the raw response body when it was not a JSON envelope
+	Body string
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	if e.StatusCode >= 200 && e.StatusCode < 300 {
+		sb.WriteString("request was not successful")
+	} else {
+		fmt.Fprintf(&sb, "unexpected status code: %d", e.StatusCode)
+	}
+
+	if len(e.Errors) > 0 {
+		sb.WriteString(": ")
+		for i, detail := range e.Errors {
+			if i > 0 {
+				sb.WriteString("; ")
+			}
+			if detail.Code != 0 {
+				fmt.Fprintf(&sb, "%d ", detail.Code)
+			}
+			sb.WriteString(detail.Message)
+		}
+	} else if e.Body != "" {
+		sb.WriteString(", body: " + e.Body)
+	}
+
+	if e.RayID != "" {
+		sb.WriteString(" (ray " + e.RayID + ")")
+	}
+
+	return sb.String()
+}
+
+// HasCode reports whether the response contained an error with the given Cloudflare error code
+func (e *APIError) HasCode(code int) bool {
+	for _, detail := range e.Errors {
+		if detail.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// DocumentationURL returns the first documentation link among the errors, if any
+func (e *APIError) DocumentationURL() string {
+	for _, detail := range e.Errors {
+		if detail.DocumentationURL != "" {
+			return detail.DocumentationURL
+		}
+	}
+	return ""
+}
+
+// Cloudflare error codes that the helpers below recognize regardless of HTTP status
+const (
+	codeAuthenticationError = 10000
+	codeInvalidToken        = 9109
+	codeKVKeyNotFound       = 10009
+	codeKVNamespaceNotFound = 10013
+	codeR2NoSuchBucket      = 10006
+	codeR2NoSuchKey         = 10007
+	codeRateLimited         = 971
+)
+
+// ErrNotFound is matched by errors returned when a lookup by name, such as
+// DatabaseByName, finds no resource
+var ErrNotFound = errors.New("not found")
+
+// IsNotFound reports whether err is an *APIError for a missing resource or
+// matches ErrNotFound
+func IsNotFound(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound ||
+		apiErr.HasCode(codeKVKeyNotFound) ||
+		apiErr.HasCode(codeKVNamespaceNotFound) ||
+		apiErr.HasCode(codeR2NoSuchBucket) ||
+		apiErr.HasCode(codeR2NoSuchKey)
+}
+
+// IsRateLimited reports whether err is an *APIError caused by rate limiting
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.HasCode(codeRateLimited)
+}
+
+// IsAuth reports whether err is an *APIError caused by a missing, invalid or
+// insufficiently privileged API token
+func IsAuth(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized ||
+		apiErr.StatusCode == http.StatusForbidden ||
+		apiErr.HasCode(codeAuthenticationError) ||
+		apiErr.HasCode(codeInvalidToken)
+}
+
+// maxErrorBody limits how much of an error response is read into an APIError
+const maxErrorBody = 64 << 10
+
+// newAPIError reads an unsuccessful response into an *APIError
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return newAPIErrorFromBody(resp, body)
+}
+
+// newAPIErrorFromBody is newAPIError for a response whose body was already read
+func newAPIErrorFromBody(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RayID:      resp.Header.Get("Cf-Ray"),
+	}
+
+	var envelope struct {
+		Errors   []ErrorDetail `json:"errors"`
+		Messages []ErrorDetail `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && (len(envelope.Errors) > 0 || len(envelope.Messages) > 0) {
+		apiErr.Errors = envelope.Errors
+		apiErr.Messages = envelope.Messages
+	} else {
+		apiErr.Body = strings.TrimSpace(string(body))
+	}
+
+	return apiErr
+}
@@ -0,0 +1,150 @@
+// Package sqlsplit splits SQLite scripts into individual statements.
+package sqlsplit
+
+import (
+	"strings"
+)
+
+// Split splits query into statements on semicolons outside of quotes,
+// comments and trigger bodies. Statements are trimmed and those containing
+// only comments are dropped.
+func Split(query string) []string {
+	var statements []string
+	add := func(stmt string) {
+		if stmt = strings.TrimSpace(stmt); stmt != "" && !onlyComments(stmt) {
+			statements = append(statements, stmt)
+		}
+	}
+
+	start := 0
+
+	// keywords holds the first keywords of the current statement, enough to
+	// recognise CREATE [TEMP] TRIGGER
+	var keywords []string
+	trigger := false
+
+	// depth counts the BEGIN and CASE blocks of a trigger that have not been closed by END
+	depth := 0
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			i = skipQuoted(query, i)
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			i += j
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				i = len(query)
+			} else {
+				i += 2 + j + 2
+			}
+		case isIdentByte(c):
+			j := i
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			word := strings.ToUpper(query[i:j])
+			i = j
+
+			if len(keywords) < 3 {
+				keywords = append(keywords, word)
+				trigger = isCreateTrigger(keywords)
+			}
+			if !trigger {
+				continue
+			}
+			switch word {
+			case "BEGIN", "CASE":
+				depth++
+			case "END":
+				if depth > 0 {
+					depth--
+				}
+			}
+		case c == ';':
+			i++
+			if trigger && depth > 0 {
+				continue
+			}
+			add(query[start : i-1])
+			start = i
+			keywords = keywords[:0]
+			trigger = false
+			depth = 0
+		default:
+			i++
+		}
+	}
+	add(query[start:])
+
+	return statements
+}
+
+// skipQuoted returns the index just past the quoted string or identifier starting at i.
+// Doubled quotes are escapes, except inside brackets.
+func skipQuoted(query string, i int) int {
+	end := query[i]
+	if end == '[' {
+		end = ']'
+	}
+	for j := i + 1; j < len(query); j++ {
+		if query[j] != end {
+			continue
+		}
+		if end != ']' && j+1 < len(query) && query[j+1] == end {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(query)
+}
+
+// isCreateTrigger reports whether the leading keywords of a statement start a trigger definition
+func isCreateTrigger(keywords []string) bool {
+	if len(keywords) < 2 || keywords[0] != "CREATE" {
+		return false
+	}
+	switch keywords[1] {
+	case "TRIGGER":
+		return true
+	case "TEMP", "TEMPORARY":
+		return len(keywords) > 2 && keywords[2] == "TRIGGER"
+	}
+	return false
+}
+
+// isIdentByte reports whether c can be part of a keyword or unquoted identifier
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= 0x80
+}
+
+// onlyComments reports whether stmt contains nothing but comments
+func onlyComments(stmt string) bool {
+	for stmt != "" {
+		stmt = strings.TrimSpace(stmt)
+		switch {
+		case strings.HasPrefix(stmt, "--"):
+			i := strings.IndexByte(stmt, '\n')
+			if i < 0 {
+				return true
+			}
+			stmt = stmt[i+1:]
+		case strings.HasPrefix(stmt, "/*"):
+			i := strings.Index(stmt, "*/")
+			if i < 0 {
+				return true
+			}
+			stmt = stmt[i+2:]
+		default:
+			return stmt == ""
+		}
+	}
+	return true
+}
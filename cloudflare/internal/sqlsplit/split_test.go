@@ -0,0 +1,75 @@
+package sqlsplit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "statements",
+			query: "CREATE TABLE a (x TEXT);\nINSERT INTO a VALUES ('x;y');\n\n",
+			want:  []string{"CREATE TABLE a (x TEXT)", "INSERT INTO a VALUES ('x;y')"},
+		},
+		{
+			name:  "quotes and comments",
+			query: `INSERT INTO "a;b" VALUES ('it''s; here'); -- done; really` + "\n/* ; */ SELECT [x;y] FROM t",
+			want:  []string{`INSERT INTO "a;b" VALUES ('it''s; here')`, "-- done; really\n/* ; */ SELECT [x;y] FROM t"},
+		},
+		{
+			name:  "only comments",
+			query: "SELECT 1; -- trailing comment\n/* block */",
+			want:  []string{"SELECT 1"},
+		},
+		{
+			name: "trigger after comments",
+			query: `-- Audit every insert
+/* written; by trigger */
+CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN
+    INSERT INTO audit (user_id) VALUES (new.id);
+    UPDATE stats SET users = users + 1;
+END;
+SELECT 1;`,
+			want: []string{`-- Audit every insert
+/* written; by trigger */
+CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN
+    INSERT INTO audit (user_id) VALUES (new.id);
+    UPDATE stats SET users = users + 1;
+END`, "SELECT 1"},
+		},
+		{
+			name: "trigger with CASE",
+			query: `CREATE TEMP TRIGGER grade AFTER UPDATE ON scores
+WHEN CASE WHEN new.value > 0 THEN 1 ELSE 0 END
+BEGIN
+    UPDATE scores SET grade = CASE WHEN new.value >= 50 THEN 'pass' ELSE 'fail' END WHERE id = new.id;
+    INSERT INTO log VALUES ('end;');
+END;
+DROP TABLE x;`,
+			want: []string{`CREATE TEMP TRIGGER grade AFTER UPDATE ON scores
+WHEN CASE WHEN new.value > 0 THEN 1 ELSE 0 END
+BEGIN
+    UPDATE scores SET grade = CASE WHEN new.value >= 50 THEN 'pass' ELSE 'fail' END WHERE id = new.id;
+    INSERT INTO log VALUES ('end;');
+END`, "DROP TABLE x"},
+		},
+		{
+			name:  "transaction keywords outside triggers",
+			query: "BEGIN; SELECT CASE WHEN 1 THEN 2 END; END;",
+			want:  []string{"BEGIN", "SELECT CASE WHEN 1 THEN 2 END", "END"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Split(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,146 +4,385 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // KVClient provides access to Cloudflare KV storage
 type KVClient struct {
 	config *CloudflareConfig
 	client *http.Client
+	tel    *telemetry
 }
 
 // KVKey represents a key in KV storage
 type KVKey struct {
-	Name       string `json:"name"`
-	Expiration int64  `json:"expiration,omitempty"`
+	Name string `json:"name"`
+
+	// Expiration is the Unix timestamp at which the key expires, or zero
+	Expiration int64 `json:"expiration,omitempty"`
+
+	// Metadata is the JSON metadata stored with the key, if any
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// UnmarshalMetadata decodes the key's metadata into target
+func (k KVKey) UnmarshalMetadata(target interface{}) error {
+	if len(k.Metadata) == 0 {
+		return fmt.Errorf("key has no metadata: %s", k.Name)
+	}
+	if err := json.Unmarshal(k.Metadata, target); err != nil {
+		return fmt.Errorf("error decoding metadata: %w", err)
+	}
+	return nil
 }
 
-// NewKVClient creates a new KVClient with the provided configuration
-func NewKVClient(config *CloudflareConfig) *KVClient {
+// MaxKVMetadataSize is the maximum size of a key's serialized JSON metadata
+const MaxKVMetadataSize = 1024
+
+// NewKVClient creates a new KVClient with the provided configuration and options
+func NewKVClient(config *CloudflareConfig, opts ...Option) *KVClient {
 	if config.BaseURL == "" {
 		config.BaseURL = "https://api.cloudflare.com/client/v4"
 	}
 
 	return &KVClient{
 		config: config,
-		client: createHTTPClient(config),
+		client: createHTTPClient(config, opts...),
+		tel:    newTelemetry(config, serviceKV),
 	}
 }
 
-// ListKeys lists keys in a KV namespace with an optional prefix
-func (k *KVClient) ListKeys(ctx context.Context, namespaceID, prefix string) ([]KVKey, error) {
-	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/keys",
-		k.config.BaseURL, k.config.AccountID, namespaceID)
+// Limits on the number of keys returned per page by the list keys endpoint
+const (
+	MinKVListLimit = 10
+	MaxKVListLimit = 1000
+)
+
+// ListKeysOptions filters and pages the keys returned by ListKeysPage
+type ListKeysOptions struct {
+	// Prefix only returns keys that start with it
+	Prefix string
+
+	// Limit is the maximum number of keys per page, between MinKVListLimit
+	// and MaxKVListLimit; zero uses the API default of 1000
+	Limit int
 
-	if prefix != "" {
-		urlPath = fmt.Sprintf("%s?prefix=%s", urlPath, url.QueryEscape(prefix))
+	// Cursor continues a previous listing from KVKeysPage.Cursor
+	Cursor string
+}
+
+// KVKeysPage is one page of keys in a KV namespace
+type KVKeysPage struct {
+	Keys []KVKey
+
+	// Cursor fetches the next page when passed in ListKeysOptions; it is
+	// empty on the last page
+	Cursor string
+}
+
+// ListKeys lists every key in a KV namespace with an optional prefix
+func (k *KVClient) ListKeys(ctx context.Context, namespaceID, prefix string) (_ []KVKey, err error) {
+	ctx, op := k.tel.start(ctx, "ListKeys", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	return k.listAllKeys(ctx, namespaceID, ListKeysOptions{Prefix: prefix})
+}
+
+// ListKeysPage lists a single page of keys in a KV namespace
+// Example usage:
+//
+//	opts := cloudflare.ListKeysOptions{Prefix: "user:", Limit: 100}
+//	for {
+//	    page, err := kvClient.ListKeysPage(ctx, namespaceID, opts)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    process(page.Keys)
+//	    if page.Cursor == "" {
+//	        break
+//	    }
+//	    opts.Cursor = page.Cursor
+//	}
+func (k *KVClient) ListKeysPage(ctx context.Context, namespaceID string, opts ListKeysOptions) (_ *KVKeysPage, err error) {
+	ctx, op := k.tel.start(ctx, "ListKeysPage", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	if opts.Limit != 0 && (opts.Limit < MinKVListLimit || opts.Limit > MaxKVListLimit) {
+		return nil, fmt.Errorf("limit must be between %d and %d, got %d", MinKVListLimit, MaxKVListLimit, opts.Limit)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlPath, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+	query := url.Values{}
+	if opts.Prefix != "" {
+		query.Set("prefix", opts.Prefix)
+	}
+	if opts.Limit != 0 {
+		query.Set("limit", fmt.Sprint(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
 	}
 
-	req.Header.Add("Authorization", "Bearer "+k.config.APIToken)
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/keys",
+		k.config.BaseURL, k.config.AccountID, namespaceID)
+	if len(query) > 0 {
+		urlPath += "?" + query.Encode()
+	}
 
-	resp, err := k.client.Do(req)
+	req, err := newAPIRequest(ctx, k.config, http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	resp, err := doAPIRequest(k.client, req)
+	if err != nil {
+		return nil, err
 	}
 
-	var response struct {
-		Success bool    `json:"success"`
-		Result  []KVKey `json:"result"`
+	var page KVKeysPage
+	var info struct {
+		Cursor string `json:"cursor"`
 	}
+	if err := decodePagedAPIResponse(resp, &page.Keys, &info); err != nil {
+		return nil, err
+	}
+	page.Cursor = info.Cursor
+
+	return &page, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+// ListAllKeys lists every key matching opts, following cursors until the last
+// page. opts.Limit sets the page size and opts.Cursor where to start.
+func (k *KVClient) ListAllKeys(ctx context.Context, namespaceID string, opts ListKeysOptions) (_ []KVKey, err error) {
+	ctx, op := k.tel.start(ctx, "ListAllKeys", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	return k.listAllKeys(ctx, namespaceID, opts)
+}
+
+func (k *KVClient) listAllKeys(ctx context.Context, namespaceID string, opts ListKeysOptions) ([]KVKey, error) {
+	var keys []KVKey
+	for key, err := range k.Keys(ctx, namespaceID, opts) {
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
 	}
 
-	if !response.Success {
-		return nil, fmt.Errorf("request was not successful")
+	return keys, nil
+}
+
+// Keys returns an iterator over every key matching opts that fetches them a
+// page at a time. Iteration stops after the first error, including when ctx
+// is canceled between pages.
+// Example usage:
+//
+//	for key, err := range kvClient.Keys(ctx, namespaceID, cloudflare.ListKeysOptions{Prefix: "session:"}) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Println(key.Name)
+//	}
+func (k *KVClient) Keys(ctx context.Context, namespaceID string, opts ListKeysOptions) iter.Seq2[KVKey, error] {
+	return func(yield func(KVKey, error) bool) {
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(KVKey{}, err)
+				return
+			}
+
+			page, err := k.ListKeysPage(ctx, namespaceID, opts)
+			if err != nil {
+				yield(KVKey{}, err)
+				return
+			}
+
+			for _, key := range page.Keys {
+				if !yield(key, nil) {
+					return
+				}
+			}
+
+			if page.Cursor == "" || page.Cursor == opts.Cursor {
+				return
+			}
+			opts.Cursor = page.Cursor
+		}
 	}
+}
 
-	return response.Result, nil
+// MinKVExpirationTTL is the shortest time to live Cloudflare accepts for a key
+const MinKVExpirationTTL = 60 * time.Second
+
+// ErrKVExpirationTooSoon is matched by the error returned when a write expires
+// sooner than MinKVExpirationTTL from now
+var ErrKVExpirationTooSoon = errors.New("expiration must be at least 60 seconds in the future")
+
+// KVExpirationError reports a write whose TTL or expiration time is too soon
+type KVExpirationError struct {
+	Key string
+
+	// TTL is the requested time to live, or the time left until the requested
+	// expiration time
+	TTL time.Duration
 }
 
-// WriteValue writes a value to a KV namespace with an optional expiration
-func (k *KVClient) WriteValue(ctx context.Context, namespaceID, key string, value []byte, expiration *int64) error {
-	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/values/%s",
-		k.config.BaseURL, k.config.AccountID, namespaceID, url.PathEscape(key))
+func (e *KVExpirationError) Error() string {
+	return fmt.Sprintf("%s: key %s expires in %s", ErrKVExpirationTooSoon, e.Key, e.TTL)
+}
 
-	if expiration != nil {
-		urlPath = fmt.Sprintf("%s?expiration_ttl=%d", urlPath, *expiration)
+// Is reports whether target is ErrKVExpirationTooSoon
+func (e *KVExpirationError) Is(target error) bool {
+	return target == ErrKVExpirationTooSoon
+}
+
+// validateExpiration checks that at most one of ttl and expiresAt is set, that
+// ttl is a whole number of seconds and that the key expires no sooner than
+// MinKVExpirationTTL from now
+func validateExpiration(key string, ttl time.Duration, expiresAt time.Time) error {
+	switch {
+	case ttl != 0 && !expiresAt.IsZero():
+		return fmt.Errorf("key %s: only one expiration time can be set", key)
+	case ttl != 0:
+		if ttl < MinKVExpirationTTL {
+			return &KVExpirationError{Key: key, TTL: ttl}
+		}
+		// Cloudflare takes the TTL in seconds; refuse to round it silently
+		if ttl%time.Second != 0 {
+			return fmt.Errorf("key %s: TTL %s is not a whole number of seconds", key, ttl)
+		}
+	case !expiresAt.IsZero():
+		if left := time.Until(expiresAt); left < MinKVExpirationTTL {
+			return &KVExpirationError{Key: key, TTL: left}
+		}
 	}
+	return nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, urlPath, bytes.NewReader(value))
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+// WriteOptions controls the expiration and metadata of a written value. At
+// most one of TTL and ExpiresAt may be set.
+type WriteOptions struct {
+	// TTL expires the key this long after the write, in whole seconds and at
+	// least MinKVExpirationTTL
+	TTL time.Duration
+
+	// ExpiresAt expires the key at this time, at least MinKVExpirationTTL from now
+	ExpiresAt time.Time
+
+	// Metadata is stored with the key as JSON of up to MaxKVMetadataSize bytes
+	// and returned when listing keys
+	Metadata interface{}
+}
+
+// WriteValue writes a value to a KV namespace. opts may be nil for a value
+// without expiration or metadata.
+// Example usage:
+//
+//	err := kvClient.WriteValue(ctx, namespaceID, "session:abc", token, &cloudflare.WriteOptions{
+//	    TTL:      time.Hour,
+//	    Metadata: map[string]string{"user": "42"},
+//	})
+//	if errors.Is(err, cloudflare.ErrKVExpirationTooSoon) {
+//	    // TTL was shorter than a minute
+//	}
+func (k *KVClient) WriteValue(ctx context.Context, namespaceID, key string, value []byte, opts *WriteOptions) (err error) {
+	ctx, op := k.tel.start(ctx, "WriteValue", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	if opts == nil {
+		opts = &WriteOptions{}
 	}
 
-	req.Header.Add("Authorization", "Bearer "+k.config.APIToken)
-	req.Header.Add("Content-Type", "application/octet-stream")
+	if err := validateExpiration(key, opts.TTL, opts.ExpiresAt); err != nil {
+		return err
+	}
 
-	resp, err := k.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+	query := url.Values{}
+	switch {
+	case opts.TTL != 0:
+		query.Set("expiration_ttl", fmt.Sprint(int64(opts.TTL/time.Second)))
+	case !opts.ExpiresAt.IsZero():
+		query.Set("expiration", fmt.Sprint(opts.ExpiresAt.Unix()))
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	var metadata []byte
+	if opts.Metadata != nil {
+		metadata, err = json.Marshal(opts.Metadata)
+		if err != nil {
+			return fmt.Errorf("error encoding metadata to JSON: %w", err)
+		}
+		if len(metadata) > MaxKVMetadataSize {
+			return fmt.Errorf("metadata is %d bytes, the limit is %d", len(metadata), MaxKVMetadataSize)
+		}
 	}
 
-	var response struct {
-		Success bool `json:"success"`
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/values/%s",
+		k.config.BaseURL, k.config.AccountID, namespaceID, url.PathEscape(key))
+	if len(query) > 0 {
+		urlPath += "?" + query.Encode()
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("error decoding response: %w", err)
+	// Values with metadata are sent as a multipart form
+	body := bytes.NewBuffer(value)
+	contentType := "application/octet-stream"
+	if metadata != nil {
+		body = &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		if err := form.WriteField("value", string(value)); err != nil {
+			return fmt.Errorf("error encoding value: %w", err)
+		}
+		if err := form.WriteField("metadata", string(metadata)); err != nil {
+			return fmt.Errorf("error encoding metadata: %w", err)
+		}
+		if err := form.Close(); err != nil {
+			return fmt.Errorf("error encoding form: %w", err)
+		}
+		contentType = form.FormDataContentType()
 	}
 
-	if !response.Success {
-		return fmt.Errorf("request was not successful")
+	req, err := newAPIRequest(ctx, k.config, http.MethodPut, urlPath, body)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	req.Header.Add("Content-Type", contentType)
+
+	resp, err := doAPIRequest(k.client, req)
+	if err != nil {
+		return err
+	}
+
+	return decodeAPIResponse(resp, nil)
 }
 
 // ReadValue reads a value from a KV namespace
-func (k *KVClient) ReadValue(ctx context.Context, namespaceID, key string) ([]byte, error) {
+func (k *KVClient) ReadValue(ctx context.Context, namespaceID, key string) (_ []byte, err error) {
+	ctx, op := k.tel.start(ctx, "ReadValue", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
 	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/values/%s",
 		k.config.BaseURL, k.config.AccountID, namespaceID, url.PathEscape(key))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlPath, nil)
+	req, err := newAPIRequest(ctx, k.config, http.MethodGet, urlPath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+k.config.APIToken)
-
-	resp, err := k.client.Do(req)
+	resp, err := doAPIRequest(k.client, req)
+	if IsNotFound(err) {
+		return nil, fmt.Errorf("key not found: %s: %w", key, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("key not found: %s", key)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error reading response body: %w", err)
@@ -152,51 +391,95 @@ func (k *KVClient) ReadValue(ctx context.Context, namespaceID, key string) ([]by
 	return body, nil
 }
 
-// DeleteValue deletes a value from a KV namespace
-func (k *KVClient) DeleteValue(ctx context.Context, namespaceID, key string) error {
-	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/values/%s",
+// ReadMetadata reads the JSON metadata of a key without fetching its value.
+// It returns nil if the key has no metadata.
+func (k *KVClient) ReadMetadata(ctx context.Context, namespaceID, key string) (_ json.RawMessage, err error) {
+	ctx, op := k.tel.start(ctx, "ReadMetadata", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/metadata/%s",
 		k.config.BaseURL, k.config.AccountID, namespaceID, url.PathEscape(key))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, urlPath, nil)
+	req, err := newAPIRequest(ctx, k.config, http.MethodGet, urlPath, nil)
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+k.config.APIToken)
-
-	resp, err := k.client.Do(req)
+	resp, err := doAPIRequest(k.client, req)
+	if IsNotFound(err) {
+		return nil, fmt.Errorf("key not found: %s: %w", key, err)
+	}
 	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	var metadata json.RawMessage
+	if err := decodeAPIResponse(resp, &metadata); err != nil {
+		return nil, err
+	}
+	if string(metadata) == "null" {
+		return nil, nil
+	}
+
+	return metadata, nil
+}
+
+// ReadValueWithMetadata reads a value and its JSON metadata, which is nil if
+// the key has none
+// Example usage:
+//
+//	value, meta, err := kvClient.ReadValueWithMetadata(ctx, namespaceID, "page:home")
+//	if err != nil {
+//	    log.Fatalf("Failed to read value: %v", err)
+//	}
+//	var info struct{ Version int `json:"version"` }
+//	json.Unmarshal(meta, &info)
+func (k *KVClient) ReadValueWithMetadata(ctx context.Context, namespaceID, key string) (_ []byte, _ json.RawMessage, err error) {
+	ctx, op := k.tel.start(ctx, "ReadValueWithMetadata", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	value, err := k.ReadValue(ctx, namespaceID, key)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var response struct {
-		Success bool `json:"success"`
+	metadata, err := k.ReadMetadata(ctx, namespaceID, key)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("error decoding response: %w", err)
+	return value, metadata, nil
+}
+
+// DeleteValue deletes a value from a KV namespace
+func (k *KVClient) DeleteValue(ctx context.Context, namespaceID, key string) (err error) {
+	ctx, op := k.tel.start(ctx, "DeleteValue", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/values/%s",
+		k.config.BaseURL, k.config.AccountID, namespaceID, url.PathEscape(key))
+
+	req, err := newAPIRequest(ctx, k.config, http.MethodDelete, urlPath, nil)
+	if err != nil {
+		return err
 	}
 
-	if !response.Success {
-		return fmt.Errorf("request was not successful")
+	resp, err := doAPIRequest(k.client, req)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return decodeAPIResponse(resp, nil)
 }
 
-// WriteJSON writes a JSON value to a KV namespace
-func (k *KVClient) WriteJSON(ctx context.Context, namespaceID, key string, value interface{}, expiration *int64) error {
+// WriteJSON writes a JSON value to a KV namespace. opts may be nil.
+func (k *KVClient) WriteJSON(ctx context.Context, namespaceID, key string, value interface{}, opts *WriteOptions) error {
 	jsonData, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("error encoding value to JSON: %w", err)
 	}
 
-	return k.WriteValue(ctx, namespaceID, key, jsonData, expiration)
+	return k.WriteValue(ctx, namespaceID, key, jsonData, opts)
 }
 
 // ReadJSON reads a JSON value from a KV namespace and unmarshals it into the target
@@ -0,0 +1,270 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+/*
+* https://developers.cloudflare.com/api/resources/kv/subresources/namespaces/methods/bulk_update/
+* https://developers.cloudflare.com/api/resources/kv/subresources/namespaces/methods/bulk_delete/
+* https://developers.cloudflare.com/api/resources/kv/subresources/namespaces/methods/bulk_get/
+ */
+
+// Limits of the KV bulk endpoints. Bulk calls split larger inputs into
+// several requests that respect them.
+const (
+	MaxKVBulkWriteKeys  = 10000
+	MaxKVBulkWriteBytes = 100 << 20
+	MaxKVBulkDeleteKeys = 10000
+	MaxKVBulkGetKeys    = 100
+)
+
+// KVPair is a key and value written by BulkWrite
+type KVPair struct {
+	Key string `json:"key"`
+
+	// Value is the value to store; set Base64 if it is base64 encoded binary data
+	Value  string `json:"value"`
+	Base64 bool   `json:"base64,omitempty"`
+
+	// Expiration is a Unix timestamp and ExpirationTTL a number of seconds,
+	// at least 60, after which the key expires
+	Expiration    int64 `json:"expiration,omitempty"`
+	ExpirationTTL int64 `json:"expiration_ttl,omitempty"`
+
+	// Metadata is stored with the key and serialized as JSON
+	Metadata interface{} `json:"metadata,omitempty"`
+}
+
+// KVBulkResult combines the results of every request made by a bulk call
+type KVBulkResult struct {
+	// SuccessfulKeyCount is the number of keys written or deleted
+	SuccessfulKeyCount int `json:"successful_key_count"`
+
+	// UnsuccessfulKeys are keys that could not be written or deleted
+	UnsuccessfulKeys []string `json:"unsuccessful_keys"`
+}
+
+// KVValue is a value returned by BulkGet
+type KVValue struct {
+	Value    string          `json:"value"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// KVBulkGetResult is the result of BulkGet
+type KVBulkGetResult struct {
+	// Values maps each key that was found to its value
+	Values map[string]KVValue
+
+	// Missing are the requested keys that do not exist
+	Missing []string
+}
+
+// BulkWrite writes many key-value pairs, sending up to MaxKVBulkWriteKeys pairs
+// per request. Keys the API rejects are reported in the result's
+// UnsuccessfulKeys. If a request fails, the result covers the requests that
+// completed before it. Expirations are validated like those of WriteValue
+// before anything is sent, so a pair expiring too soon returns a
+// *KVExpirationError and writes nothing.
+// Example usage:
+//
+//	result, err := kvClient.BulkWrite(ctx, namespaceID, []cloudflare.KVPair{
+//	    {Key: "user:1", Value: `{"name":"Ada"}`, Metadata: map[string]int{"version": 1}},
+//	    {Key: "session:abc", Value: "1", ExpirationTTL: 3600},
+//	})
+//	if err != nil {
+//	    log.Fatalf("Failed to write keys: %v", err)
+//	}
+//	fmt.Println("failed keys:", result.UnsuccessfulKeys)
+func (k *KVClient) BulkWrite(ctx context.Context, namespaceID string, pairs []KVPair) (_ *KVBulkResult, err error) {
+	ctx, op := k.tel.start(ctx, "BulkWrite", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	for _, pair := range pairs {
+		var expiresAt time.Time
+		if pair.Expiration != 0 {
+			expiresAt = time.Unix(pair.Expiration, 0)
+		}
+		if err := validateExpiration(pair.Key, time.Duration(pair.ExpirationTTL)*time.Second, expiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/bulk",
+		k.config.BaseURL, k.config.AccountID, namespaceID)
+
+	result := &KVBulkResult{}
+	for start := 0; start < len(pairs); {
+		encoded, err := encodeBulkWriteChunk(pairs[start:])
+		if err != nil {
+			return result, err
+		}
+		end := start + len(encoded)
+
+		var chunk KVBulkResult
+		if err := k.do(ctx, http.MethodPut, urlPath, encoded, &chunk); err != nil {
+			return result, fmt.Errorf("error writing keys %d to %d: %w", start, end-1, err)
+		}
+		result.add(chunk)
+
+		start = end
+	}
+
+	return result, nil
+}
+
+// encodeBulkWriteChunk encodes as many of pairs as fit in a single bulk write
+// request. Sizes are measured on the encoded pairs, since JSON escaping and
+// metadata can make a pair much larger than its key and value.
+func encodeBulkWriteChunk(pairs []KVPair) ([]json.RawMessage, error) {
+	// The brackets of the array
+	size := 2
+
+	var chunk []json.RawMessage
+	for i, pair := range pairs {
+		if i == MaxKVBulkWriteKeys {
+			break
+		}
+
+		encoded, err := json.Marshal(pair)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding key %s: %w", pair.Key, err)
+		}
+
+		size += len(encoded)
+		if i > 0 {
+			// The separating comma
+			size++
+		}
+		if size > MaxKVBulkWriteBytes && i > 0 {
+			break
+		}
+
+		chunk = append(chunk, encoded)
+	}
+
+	return chunk, nil
+}
+
+// BulkDelete deletes many keys, sending up to MaxKVBulkDeleteKeys keys per
+// request. Keys that do not exist count as deleted.
+func (k *KVClient) BulkDelete(ctx context.Context, namespaceID string, keys []string) (_ *KVBulkResult, err error) {
+	ctx, op := k.tel.start(ctx, "BulkDelete", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	// Deleting a key twice has the same effect as deleting it once
+	ctx = WithIdempotent(ctx)
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/bulk/delete",
+		k.config.BaseURL, k.config.AccountID, namespaceID)
+
+	result := &KVBulkResult{}
+	for start := 0; start < len(keys); start += MaxKVBulkDeleteKeys {
+		end := min(start+MaxKVBulkDeleteKeys, len(keys))
+
+		var chunk KVBulkResult
+		if err := k.do(ctx, http.MethodPost, urlPath, keys[start:end], &chunk); err != nil {
+			return result, fmt.Errorf("error deleting keys %d to %d: %w", start, end-1, err)
+		}
+		result.add(chunk)
+	}
+
+	return result, nil
+}
+
+// BulkGet reads many text values and their metadata, sending up to
+// MaxKVBulkGetKeys keys per request. Keys that do not exist are listed in the
+// result's Missing.
+// Example usage:
+//
+//	result, err := kvClient.BulkGet(ctx, namespaceID, []string{"user:1", "user:2"})
+//	if err != nil {
+//	    log.Fatalf("Failed to read keys: %v", err)
+//	}
+//	for key, value := range result.Values {
+//	    fmt.Println(key, value.Value)
+//	}
+func (k *KVClient) BulkGet(ctx context.Context, namespaceID string, keys []string) (_ *KVBulkGetResult, err error) {
+	ctx, op := k.tel.start(ctx, "BulkGet", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	ctx = WithIdempotent(ctx)
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s/bulk/get",
+		k.config.BaseURL, k.config.AccountID, namespaceID)
+
+	result := &KVBulkGetResult{Values: make(map[string]KVValue, len(keys))}
+	for start := 0; start < len(keys); start += MaxKVBulkGetKeys {
+		end := min(start+MaxKVBulkGetKeys, len(keys))
+
+		body := struct {
+			Keys         []string `json:"keys"`
+			Type         string   `json:"type"`
+			WithMetadata bool     `json:"withMetadata"`
+		}{
+			Keys:         keys[start:end],
+			Type:         "text",
+			WithMetadata: true,
+		}
+
+		var chunk struct {
+			Values map[string]*KVValue `json:"values"`
+		}
+		if err := k.do(ctx, http.MethodPost, urlPath, body, &chunk); err != nil {
+			return result, fmt.Errorf("error reading keys %d to %d: %w", start, end-1, err)
+		}
+
+		for _, key := range keys[start:end] {
+			if value := chunk.Values[key]; value != nil {
+				if string(value.Metadata) == "null" {
+					value.Metadata = nil
+				}
+				result.Values[key] = *value
+			} else {
+				result.Missing = append(result.Missing, key)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// add merges the result of one bulk request into r
+func (r *KVBulkResult) add(chunk KVBulkResult) {
+	r.SuccessfulKeyCount += chunk.SuccessfulKeyCount
+	r.UnsuccessfulKeys = append(r.UnsuccessfulKeys, chunk.UnsuccessfulKeys...)
+}
+
+// do sends a JSON request to the KV API and decodes the result field of the
+// response envelope into result, which may be nil
+func (k *KVClient) do(ctx context.Context, method, url string, body interface{}, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshaling request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(jsonBody)
+	}
+
+	req, err := newAPIRequest(ctx, k.config, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := doAPIRequest(k.client, req)
+	if err != nil {
+		return err
+	}
+
+	return decodeAPIResponse(resp, result)
+}
@@ -0,0 +1,55 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBulkWriteChunkMeasuresEscaping(t *testing.T) {
+	// Each < is escaped to \u003c, so these values encode to six times their length
+	value := strings.Repeat("<", MaxKVBulkWriteBytes/10)
+	pairs := []KVPair{{Key: "a", Value: value}, {Key: "b", Value: value}, {Key: "c", Value: value}}
+
+	chunk, err := encodeBulkWriteChunk(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk) != 1 {
+		t.Fatalf("chunk has %d pairs, want 1", len(chunk))
+	}
+
+	body, err := json.Marshal(chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) > MaxKVBulkWriteBytes {
+		t.Errorf("request body is %d bytes, limit is %d", len(body), MaxKVBulkWriteBytes)
+	}
+}
+
+func TestEncodeBulkWriteChunkCountsMetadata(t *testing.T) {
+	metadata := map[string]string{"padding": strings.Repeat("x", 1000)}
+	value := strings.Repeat("v", 9500)
+
+	var pairs []KVPair
+	for i := 0; i < 11000; i++ {
+		pairs = append(pairs, KVPair{Key: "k", Value: value, Metadata: metadata})
+	}
+
+	chunk, err := encodeBulkWriteChunk(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := 1
+	for _, pair := range chunk {
+		size += len(pair) + 1
+	}
+	if size > MaxKVBulkWriteBytes {
+		t.Errorf("chunk of %d pairs is %d bytes, limit is %d", len(chunk), size, MaxKVBulkWriteBytes)
+	}
+	if len(chunk) == 0 || len(chunk) >= MaxKVBulkWriteKeys {
+		t.Errorf("chunk has %d pairs, want it limited by size", len(chunk))
+	}
+}
@@ -0,0 +1,139 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// KVNamespace describes a KV namespace in the account
+type KVNamespace struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+
+	// SupportsURLEncoding is true if keys in the namespace are URL decoded
+	SupportsURLEncoding bool `json:"supports_url_encoding"`
+}
+
+/*
+* https://developers.cloudflare.com/api/resources/kv/subresources/namespaces/
+ */
+
+// kvNamespacePageSize is the number of namespaces requested per page when listing
+const kvNamespacePageSize = 100
+
+// ListNamespaces lists every KV namespace in the account, fetching them a page at a time
+func (k *KVClient) ListNamespaces(ctx context.Context) (_ []KVNamespace, err error) {
+	ctx, op := k.tel.start(ctx, "ListNamespaces")
+	defer func() { op.end(err) }()
+
+	return k.listNamespaces(ctx)
+}
+
+// listNamespaces walks every page of the list endpoint
+func (k *KVClient) listNamespaces(ctx context.Context) ([]KVNamespace, error) {
+	var namespaces []KVNamespace
+
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("page", fmt.Sprint(page))
+		query.Set("per_page", fmt.Sprint(kvNamespacePageSize))
+
+		urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces?%s",
+			k.config.BaseURL, k.config.AccountID, query.Encode())
+
+		var result []KVNamespace
+		if err := k.do(ctx, http.MethodGet, urlPath, nil, &result); err != nil {
+			return nil, err
+		}
+
+		namespaces = append(namespaces, result...)
+		if len(result) < kvNamespacePageSize {
+			return namespaces, nil
+		}
+	}
+}
+
+// NamespaceByTitle returns the KV namespace with the given title
+// Example usage:
+//
+//	ns, err := kvClient.NamespaceByTitle(ctx, "sessions-"+branch)
+//	if err != nil {
+//	    log.Fatalf("Failed to find namespace: %v", err)
+//	}
+//	value, err := kvClient.ReadValue(ctx, ns.ID, "greeting")
+func (k *KVClient) NamespaceByTitle(ctx context.Context, title string) (_ *KVNamespace, err error) {
+	ctx, op := k.tel.start(ctx, "NamespaceByTitle")
+	defer func() { op.end(err) }()
+
+	namespaces, err := k.listNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range namespaces {
+		if namespaces[i].Title == title {
+			return &namespaces[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("namespace %s %w", title, ErrNotFound)
+}
+
+// CreateNamespace creates a new KV namespace. Titles must be unique within the account.
+// Example usage:
+//
+//	ns, err := kvClient.CreateNamespace(ctx, "sessions-"+branch)
+//	if err != nil {
+//	    log.Fatalf("Failed to create namespace: %v", err)
+//	}
+//	fmt.Println("created", ns.ID)
+func (k *KVClient) CreateNamespace(ctx context.Context, title string) (_ *KVNamespace, err error) {
+	ctx, op := k.tel.start(ctx, "CreateNamespace")
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces",
+		k.config.BaseURL, k.config.AccountID)
+
+	body := struct {
+		Title string `json:"title"`
+	}{
+		Title: title,
+	}
+
+	var result KVNamespace
+	if err := k.do(ctx, http.MethodPost, urlPath, body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// RenameNamespace changes the title of a KV namespace
+func (k *KVClient) RenameNamespace(ctx context.Context, namespaceID, title string) (err error) {
+	ctx, op := k.tel.start(ctx, "RenameNamespace", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s",
+		k.config.BaseURL, k.config.AccountID, namespaceID)
+
+	body := struct {
+		Title string `json:"title"`
+	}{
+		Title: title,
+	}
+
+	return k.do(ctx, http.MethodPut, urlPath, body, nil)
+}
+
+// DeleteNamespace deletes a KV namespace and every key in it
+func (k *KVClient) DeleteNamespace(ctx context.Context, namespaceID string) (err error) {
+	ctx, op := k.tel.start(ctx, "DeleteNamespace", attrKVNamespace.String(namespaceID))
+	defer func() { op.end(err) }()
+
+	urlPath := fmt.Sprintf("%s/accounts/%s/storage/kv/namespaces/%s",
+		k.config.BaseURL, k.config.AccountID, namespaceID)
+
+	return k.do(ctx, http.MethodDelete, urlPath, nil, nil)
+}
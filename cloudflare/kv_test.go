@@ -0,0 +1,173 @@
+package cloudflare_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+	"github.com/BLANK-13/go-cloud-utils/cloudflare/cftest"
+)
+
+// requestCounter is middleware that counts requests by method and path suffix
+type requestCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *requestCounter) middleware(next http.RoundTripper) http.RoundTripper {
+	return cloudflare.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		c.mu.Lock()
+		path := req.URL.Path
+		c.counts[req.Method+" "+path[strings.LastIndex(path, "/")+1:]]++
+		c.mu.Unlock()
+		return next.RoundTrip(req)
+	})
+}
+
+func (c *requestCounter) count(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[key]
+}
+
+// newKV returns a KV client for a fake server that counts its requests, and a namespace
+func newKV(t *testing.T) (*cftest.Server, *cloudflare.KVClient, *requestCounter, string) {
+	t.Helper()
+
+	srv := cftest.NewServer(t)
+	counter := &requestCounter{counts: make(map[string]int)}
+	kv := srv.Storage(cloudflare.WithMiddleware(counter.middleware)).KV
+
+	return srv, kv, counter, srv.CreateKVNamespace("test")
+}
+
+func TestKeysPagination(t *testing.T) {
+	ctx := context.Background()
+	srv, kv, counter, namespaceID := newKV(t)
+
+	for i := 0; i < 25; i++ {
+		srv.SetKVValue(namespaceID, fmt.Sprintf("user:%02d", i), []byte("x"))
+	}
+	srv.SetKVValue(namespaceID, "other", []byte("x"))
+
+	var names []string
+	for key, err := range kv.Keys(ctx, namespaceID, cloudflare.ListKeysOptions{Prefix: "user:", Limit: 10}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, key.Name)
+	}
+
+	if len(names) != 25 {
+		t.Fatalf("got %d keys, want 25", len(names))
+	}
+	for i, name := range names {
+		if want := fmt.Sprintf("user:%02d", i); name != want {
+			t.Errorf("key %d = %s, want %s", i, name, want)
+		}
+	}
+	if n := counter.count("GET keys"); n != 3 {
+		t.Errorf("made %d list requests, want 3", n)
+	}
+}
+
+func TestKeysStopsEarly(t *testing.T) {
+	srv, kv, counter, namespaceID := newKV(t)
+
+	for i := 0; i < 25; i++ {
+		srv.SetKVValue(namespaceID, fmt.Sprintf("key:%02d", i), []byte("x"))
+	}
+
+	for key, err := range kv.Keys(context.Background(), namespaceID, cloudflare.ListKeysOptions{Limit: 10}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Name == "key:04" {
+			break
+		}
+	}
+
+	if n := counter.count("GET keys"); n != 1 {
+		t.Errorf("made %d list requests, want 1", n)
+	}
+}
+
+func TestBulkWriteChunking(t *testing.T) {
+	ctx := context.Background()
+	srv, kv, counter, namespaceID := newKV(t)
+
+	pairs := make([]cloudflare.KVPair, cloudflare.MaxKVBulkWriteKeys+5)
+	for i := range pairs {
+		pairs[i] = cloudflare.KVPair{Key: fmt.Sprintf("key:%05d", i), Value: fmt.Sprint(i)}
+	}
+
+	result, err := kv.BulkWrite(ctx, namespaceID, pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.SuccessfulKeyCount != len(pairs) {
+		t.Errorf("SuccessfulKeyCount = %d, want %d", result.SuccessfulKeyCount, len(pairs))
+	}
+	if n := counter.count("PUT bulk"); n != 2 {
+		t.Errorf("made %d bulk requests, want 2", n)
+	}
+
+	last := pairs[len(pairs)-1]
+	if value, ok := srv.KVValue(namespaceID, last.Key); !ok || string(value) != last.Value {
+		t.Errorf("last key = %q, %v; want %q", value, ok, last.Value)
+	}
+}
+
+func TestWriteExpirationValidation(t *testing.T) {
+	ctx := context.Background()
+	srv, kv, counter, namespaceID := newKV(t)
+
+	err := kv.WriteValue(ctx, namespaceID, "short", []byte("x"), &cloudflare.WriteOptions{TTL: 30 * time.Second})
+	if !errors.Is(err, cloudflare.ErrKVExpirationTooSoon) {
+		t.Errorf("WriteValue with a 30s TTL: got %v, want ErrKVExpirationTooSoon", err)
+	}
+
+	err = kv.WriteValue(ctx, namespaceID, "fraction", []byte("x"), &cloudflare.WriteOptions{TTL: 90*time.Second + 500*time.Millisecond})
+	if err == nil {
+		t.Error("WriteValue with a 90.5s TTL: expected an error")
+	}
+
+	_, err = kv.BulkWrite(ctx, namespaceID, []cloudflare.KVPair{
+		{Key: "ok", Value: "x", ExpirationTTL: 3600},
+		{Key: "short", Value: "x", ExpirationTTL: 30},
+	})
+	var expErr *cloudflare.KVExpirationError
+	if !errors.As(err, &expErr) || expErr.Key != "short" {
+		t.Errorf("BulkWrite with a 30s TTL: got %v, want a *KVExpirationError for key short", err)
+	}
+
+	_, err = kv.BulkWrite(ctx, namespaceID, []cloudflare.KVPair{
+		{Key: "past", Value: "x", Expiration: time.Now().Add(10 * time.Second).Unix()},
+	})
+	if !errors.Is(err, cloudflare.ErrKVExpirationTooSoon) {
+		t.Errorf("BulkWrite expiring in 10s: got %v, want ErrKVExpirationTooSoon", err)
+	}
+
+	if n := counter.count("PUT bulk") + counter.count("PUT short") + counter.count("PUT fraction"); n != 0 {
+		t.Errorf("made %d write requests, want none", n)
+	}
+	if _, ok := srv.KVValue(namespaceID, "ok"); ok {
+		t.Error("valid pair was written although another pair was rejected")
+	}
+}
+
+func TestNamespaceByTitleNotFound(t *testing.T) {
+	_, kv, _, _ := newKV(t)
+
+	_, err := kv.NamespaceByTitle(context.Background(), "missing")
+	if !cloudflare.IsNotFound(err) {
+		t.Errorf("IsNotFound(%v) = false", err)
+	}
+}
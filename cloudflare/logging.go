@@ -0,0 +1,118 @@
+package cloudflare
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets in log output
+const redacted = "[REDACTED]"
+
+// sensitiveQueryParams are query parameter name fragments whose values are
+// redacted from logged URLs, such as the signature of presigned export URLs
+var sensitiveQueryParams = []string{"signature", "token", "credential", "key-pair", "policy"}
+
+// redactURL returns u as a string with sensitive query parameter values replaced
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	query := u.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		for _, fragment := range sensitiveQueryParams {
+			if strings.Contains(lower, fragment) {
+				query.Set(name, redacted)
+				break
+			}
+		}
+	}
+
+	copied := *u
+	copied.RawQuery = query.Encode()
+	return copied.String()
+}
+
+// redactedHeader logs an http.Header with credentials removed
+type redactedHeader http.Header
+
+func (h redactedHeader) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(h))
+	for name, values := range h {
+		switch http.CanonicalHeaderKey(name) {
+		case "Authorization", "Cookie", "Set-Cookie", "X-Auth-Key", "X-Auth-Email":
+			attrs = append(attrs, slog.String(name, redacted))
+		default:
+			attrs = append(attrs, slog.String(name, strings.Join(values, ", ")))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// loggingTransport logs every attempt of a request. Bodies are never logged,
+// so KV values, R2 objects and D1 parameters stay out of the logs.
+type loggingTransport struct {
+	base   http.RoundTripper
+	logger *slog.Logger
+	token  string
+}
+
+func newLoggingTransport(base http.RoundTripper, config *CloudflareConfig) http.RoundTripper {
+	if config.Logger == nil {
+		return base
+	}
+	return &loggingTransport{base: base, logger: config.Logger, token: config.APIToken}
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	target := t.redact(redactURL(req.URL))
+
+	t.logger.DebugContext(ctx, "cloudflare request",
+		slog.String("method", req.Method),
+		slog.String("url", target),
+		slog.Any("headers", redactedHeader(req.Header)))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.logger.WarnContext(ctx, "cloudflare request failed",
+			slog.String("method", req.Method),
+			slog.String("url", target),
+			slog.Duration("duration", elapsed),
+			slog.String("error", t.redact(err.Error())))
+		return resp, err
+	}
+
+	level := slog.LevelDebug
+	if resp.StatusCode >= 400 {
+		level = slog.LevelWarn
+	}
+	t.logger.Log(ctx, level, "cloudflare response",
+		slog.String("method", req.Method),
+		slog.String("url", target),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("duration", elapsed),
+		slog.String("cf_ray", resp.Header.Get("Cf-Ray")))
+
+	return resp, err
+}
+
+// redact removes the API token from s in case a URL or error echoes it
+func (t *loggingTransport) redact(s string) string {
+	return redactToken(s, t.token)
+}
+
+// redactToken replaces every occurrence of token in s
+func redactToken(s, token string) string {
+	if token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, token, redacted)
+}
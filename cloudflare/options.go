@@ -0,0 +1,114 @@
+package cloudflare
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the transport of a client, for example to log, sign or
+// rewrite requests. It runs inside the retry loop, so it sees every attempt.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to http.RoundTripper, which is handy for
+// writing middleware and test doubles
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Option configures how a client sends requests
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	httpClient  *http.Client
+	transport   http.RoundTripper
+	middlewares []Middleware
+}
+
+// WithHTTPClient makes the client send requests with a copy of c, keeping its
+// timeout, cookie jar and redirect policy. Its transport is wrapped with the
+// configured retry policy and middleware.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = c
+	}
+}
+
+// WithTransport sets the transport requests are finally sent through, for
+// example one with a proxy or mTLS configuration. It takes precedence over the
+// transport of a client given to WithHTTPClient.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *clientOptions) {
+		o.transport = rt
+	}
+}
+
+// WithMiddleware adds middleware around the transport. The first middleware
+// given is the outermost and sees requests first.
+// Example usage:
+//
+//	logging := func(next http.RoundTripper) http.RoundTripper {
+//	    return cloudflare.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+//	        start := time.Now()
+//	        resp, err := next.RoundTrip(req)
+//	        log.Printf("%s %s took %v", req.Method, req.URL.Path, time.Since(start))
+//	        return resp, err
+//	    })
+//	}
+//	kv := cloudflare.NewKVClient(config, cloudflare.WithMiddleware(logging))
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *clientOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// createHTTPClient creates an HTTP client with the config's timeout and retry
+// policy. Requests pass through the retry policy, telemetry, logging, then
+// every middleware in order, then the base transport.
+func createHTTPClient(config *CloudflareConfig, opts ...Option) *http.Client {
+	o := newClientOptions(opts)
+
+	client := &http.Client{Timeout: config.Timeout}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		client = &copied
+	}
+
+	transport := o.baseTransport()
+	for i := len(o.middlewares) - 1; i >= 0; i-- {
+		transport = o.middlewares[i](transport)
+	}
+
+	transport = newLoggingTransport(transport, config)
+	client.Transport = newRetryTransport(&telemetryTransport{base: transport}, config)
+
+	return client
+}
+
+// createTransferClient creates an HTTP client for presigned URLs on third-party
+// storage hosts. It uses only the base transport: middleware may add
+// Cloudflare credentials, which must never reach those hosts. It has no
+// timeout, so transfers are bounded by their context.
+func createTransferClient(opts ...Option) *http.Client {
+	return &http.Client{Transport: newClientOptions(opts).baseTransport()}
+}
+
+func newClientOptions(opts []Option) *clientOptions {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &o
+}
+
+// baseTransport returns the transport requests are finally sent through
+func (o *clientOptions) baseTransport() http.RoundTripper {
+	if o.transport != nil {
+		return o.transport
+	}
+	if o.httpClient != nil && o.httpClient.Transport != nil {
+		return o.httpClient.Transport
+	}
+	return http.DefaultTransport
+}
@@ -13,6 +13,7 @@ import (
 type R2Client struct {
 	config *CloudflareConfig
 	client *http.Client
+	tel    *telemetry
 }
 
 // R2Object represents an object in R2 storage
@@ -30,20 +31,24 @@ func (o *R2Object) GetSize() (int64, error) {
 	return o.Size.Int64()
 }
 
-// NewR2Client creates a new R2Client with the provided configuration
-func NewR2Client(config *CloudflareConfig) *R2Client {
+// NewR2Client creates a new R2Client with the provided configuration and options
+func NewR2Client(config *CloudflareConfig, opts ...Option) *R2Client {
 	if config.BaseURL == "" {
 		config.BaseURL = "https://api.cloudflare.com/client/v4"
 	}
 
 	return &R2Client{
 		config: config,
-		client: createHTTPClient(config),
+		client: createHTTPClient(config, opts...),
+		tel:    newTelemetry(config, serviceR2),
 	}
 }
 
 // ListObjects lists objects in an R2 bucket with an optional prefix
-func (r *R2Client) ListObjects(ctx context.Context, bucketName, prefix string) ([]R2Object, error) {
+func (r *R2Client) ListObjects(ctx context.Context, bucketName, prefix string) (_ []R2Object, err error) {
+	ctx, op := r.tel.start(ctx, "ListObjects", attrR2Bucket.String(bucketName))
+	defer func() { op.end(err) }()
+
 	url := fmt.Sprintf("%s/accounts/%s/r2/buckets/%s/objects",
 		r.config.BaseURL, r.config.AccountID, bucketName)
 
@@ -51,105 +56,77 @@ func (r *R2Client) ListObjects(ctx context.Context, bucketName, prefix string) (
 		url = fmt.Sprintf("%s?prefix=%s", url, prefix)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := newAPIRequest(ctx, r.config, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+r.config.APIToken)
-
-	resp, err := r.client.Do(req)
+	resp, err := doAPIRequest(r.client, req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	var response struct {
-		Success bool       `json:"success"`
-		Result  []R2Object `json:"result"`
+		return nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+	var objects []R2Object
+	if err := decodeAPIResponse(resp, &objects); err != nil {
+		return nil, err
 	}
 
-	if !response.Success {
-		return nil, fmt.Errorf("request was not successful")
-	}
-
-	return response.Result, nil
+	return objects, nil
 }
 
 // UploadObject uploads an object to an R2 bucket
-func (r *R2Client) UploadObject(ctx context.Context, bucketName, key string, data io.Reader, contentType string, metadata map[string]string) (*R2Object, error) {
+func (r *R2Client) UploadObject(ctx context.Context, bucketName, key string, data io.Reader, contentType string, metadata map[string]string) (_ *R2Object, err error) {
+	ctx, op := r.tel.start(ctx, "UploadObject", attrR2Bucket.String(bucketName))
+	defer func() { op.end(err) }()
+	op.transfer = true
+
 	url := fmt.Sprintf("%s/accounts/%s/r2/buckets/%s/objects/%s",
 		r.config.BaseURL, r.config.AccountID, bucketName, key)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, data)
+	req, err := newAPIRequest(ctx, r.config, http.MethodPut, url, data)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+r.config.APIToken)
 	if contentType != "" {
 		req.Header.Add("Content-Type", contentType)
 	}
 
 	// Add metadata headers if provided
-		for k, v := range metadata {
+	for k, v := range metadata {
 		req.Header.Add("X-Metadata-"+k, v)
 	}
 
-	resp, err := r.client.Do(req)
+	resp, err := doAPIRequest(r.client, req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	var object R2Object
+	if err := decodeAPIResponse(resp, &object); err != nil {
+		return nil, err
 	}
 
-	var response struct {
-		Success bool     `json:"success"`
-		Result  R2Object `json:"result"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
-	}
-
-	if !response.Success {
-		return nil, fmt.Errorf("request was not successful")
-	}
-
-	return &response.Result, nil
+	return &object, nil
 }
 
 // GetObject retrieves an object from an R2 bucket
-func (r *R2Client) GetObject(ctx context.Context, bucketName, key string) (io.ReadCloser, map[string]string, error) {
+func (r *R2Client) GetObject(ctx context.Context, bucketName, key string) (_ io.ReadCloser, _ map[string]string, err error) {
+	ctx, op := r.tel.start(ctx, "GetObject", attrR2Bucket.String(bucketName))
+	defer func() { op.end(err) }()
+	op.transfer = true
+
 	url := fmt.Sprintf("%s/accounts/%s/r2/buckets/%s/objects/%s",
 		r.config.BaseURL, r.config.AccountID, bucketName, key)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := newAPIRequest(ctx, r.config, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error creating request: %w", err)
+		return nil, nil, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+r.config.APIToken)
-
-	resp, err := r.client.Do(req)
+	resp, err := doAPIRequest(r.client, req)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error making request: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, nil, err
 	}
 
 	// Extract metadata from headers
@@ -165,26 +142,23 @@ func (r *R2Client) GetObject(ctx context.Context, bucketName, key string) (io.Re
 }
 
 // DeleteObject deletes an object from an R2 bucket
-func (r *R2Client) DeleteObject(ctx context.Context, bucketName, key string) error {
+func (r *R2Client) DeleteObject(ctx context.Context, bucketName, key string) (err error) {
+	ctx, op := r.tel.start(ctx, "DeleteObject", attrR2Bucket.String(bucketName))
+	defer func() { op.end(err) }()
+
 	url := fmt.Sprintf("%s/accounts/%s/r2/buckets/%s/objects/%s",
 		r.config.BaseURL, r.config.AccountID, bucketName, key)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	req, err := newAPIRequest(ctx, r.config, http.MethodDelete, url, nil)
 	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+		return err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+r.config.APIToken)
-
-	resp, err := r.client.Do(req)
+	resp, err := doAPIRequest(r.client, req)
 	if err != nil {
-		return fmt.Errorf("error making request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -0,0 +1,182 @@
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how requests to the Cloudflare API are retried.
+//
+// A 429 response is retried for every request whose body can be replayed, since
+// Cloudflare did not process it. 5xx responses and network errors are only
+// retried for idempotent requests: GET, HEAD, OPTIONS, PUT and DELETE, and any
+// request whose context was marked with WithIdempotent. Read-only D1 queries are
+// marked automatically; D1 writes are not, so they are never replayed after a
+// failure that may have applied them.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+
+	// InitialBackoff is the upper bound of the first randomized delay, which
+	// doubles after every attempt up to MaxBackoff
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the computed delay and the delay requested by a
+	// Retry-After header, so a server cannot stall a caller indefinitely
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewConfig
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent marks requests made with ctx as safe to replay after a 5xx
+// response or network error, for example a D1 INSERT OR IGNORE
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+type noRetryKey struct{}
+
+// withoutRetry disables the retry policy for requests made with ctx, for
+// callers that retry on their own
+func withoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// retryTransport retries requests according to a RetryPolicy
+type retryTransport struct {
+	base   http.RoundTripper
+	policy RetryPolicy
+	logger *slog.Logger
+	token  string
+}
+
+func newRetryTransport(base http.RoundTripper, config *CloudflareConfig) http.RoundTripper {
+	policy := config.Retry
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return base
+	}
+
+	t := &retryTransport{base: base, policy: *policy, logger: config.Logger, token: config.APIToken}
+	t.policy.InitialBackoff = max(t.policy.InitialBackoff, 0)
+	t.policy.MaxBackoff = max(t.policy.MaxBackoff, t.policy.InitialBackoff)
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if disabled, _ := ctx.Value(noRetryKey{}).(bool); disabled {
+		return t.base.RoundTrip(req)
+	}
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	idempotent := isIdempotent(req)
+	backoff := t.policy.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+
+		last := attempt >= t.policy.MaxAttempts || !replayable
+		if last || !t.shouldRetry(ctx, resp, err, idempotent) {
+			return resp, err
+		}
+
+		delay := time.Duration(rand.Int64N(int64(backoff) + 1))
+		var reason slog.Attr
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(retryAfter, t.policy.MaxBackoff)
+			}
+			reason = slog.Int("status", resp.StatusCode)
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+		} else {
+			reason = slog.String("error", redactToken(err.Error(), t.token))
+		}
+
+		if t.logger != nil {
+			t.logger.WarnContext(ctx, "retrying cloudflare request",
+				slog.String("method", req.Method),
+				slog.String("url", redactToken(redactURL(req.URL), t.token)),
+				slog.Int("attempt", attempt),
+				slog.Duration("delay", delay),
+				reason)
+		}
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		if op, ok := ctx.Value(operationKey{}).(*operation); ok {
+			op.retry(attempt + 1)
+		}
+
+		backoff = min(backoff*2, t.policy.MaxBackoff)
+	}
+}
+
+func (t *retryTransport) shouldRetry(ctx context.Context, resp *http.Response, err error, idempotent bool) bool {
+	if err != nil {
+		// A cancelled request must not be retried
+		return idempotent && ctx.Err() == nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package cloudflare
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	attempts := 0
+	base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+		if attempts == 1 {
+			resp.StatusCode = http.StatusTooManyRequests
+			resp.Header.Set("Retry-After", "3600")
+		}
+		return resp, nil
+	})
+
+	transport := newRetryTransport(base, &CloudflareConfig{
+		Retry: &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+	})
+	req, _ := http.NewRequest(http.MethodGet, "https://api.cloudflare.com/client/v4/accounts", nil)
+
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || attempts != 2 {
+		t.Errorf("status %d after %d attempts, want 200 after 2", resp.StatusCode, attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %s for Retry-After, want at most MaxBackoff", elapsed)
+	}
+}
+
+func TestRetryLogRedactsToken(t *testing.T) {
+	const token = "secret-api-token"
+	var logs bytes.Buffer
+
+	base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("dial failed for Bearer " + token)
+	})
+	transport := newRetryTransport(base, &CloudflareConfig{
+		APIToken: token,
+		Logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+		Retry:    &RetryPolicy{MaxAttempts: 2},
+	})
+	req, _ := http.NewRequest(http.MethodGet, "https://api.cloudflare.com/client/v4/accounts", nil)
+
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(logs.String(), "retrying cloudflare request") {
+		t.Fatalf("retry was not logged: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), token) {
+		t.Errorf("log contains the API token: %s", logs.String())
+	}
+}
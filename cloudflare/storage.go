@@ -16,23 +16,25 @@ type Storage struct {
 	KV *KVClient
 }
 
-// NewStorage creates a new Storage instance with all clients initialized
-func NewStorage(config *CloudflareConfig) *Storage {
+// NewStorage creates a new Storage instance with all clients initialized.
+// The options are applied to every client.
+func NewStorage(config *CloudflareConfig, opts ...Option) *Storage {
 	return &Storage{
-		D1: NewD1Client(config),
-		R2: NewR2Client(config),
-		KV: NewKVClient(config),
+		D1: NewD1Client(config, opts...),
+		R2: NewR2Client(config, opts...),
+		KV: NewKVClient(config, opts...),
 	}
 }
 
 // NewStorageFromEnv creates a new Storage instance using environment variables
 // CLOUDFLARE_API_TOKEN and CLOUDFLARE_ACCOUNT_ID
-func NewStorageFromEnv() *Storage {
+func NewStorageFromEnv(opts ...Option) *Storage {
 	config := &CloudflareConfig{
 		APIToken:  os.Getenv("CLOUDFLARE_API_TOKEN"),
 		AccountID: os.Getenv("CLOUDFLARE_ACCOUNT_ID"),
 		BaseURL:   "https://api.cloudflare.com/client/v4",
+		Retry:     DefaultRetryPolicy(),
 	}
 
-	return NewStorage(config)
+	return NewStorage(config, opts...)
 }
@@ -0,0 +1,276 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies this package to OpenTelemetry providers
+const instrumentationName = "github.com/BLANK-13/go-cloud-utils/cloudflare"
+
+// Attribute keys recorded on spans and metrics
+const (
+	attrService     = attribute.Key("cloudflare.service")
+	attrOperation   = attribute.Key("cloudflare.operation")
+	attrKVNamespace = attribute.Key("cloudflare.kv.namespace_id")
+	attrR2Bucket    = attribute.Key("cloudflare.r2.bucket")
+	attrD1Database  = attribute.Key("cloudflare.d1.database_id")
+	attrRayID       = attribute.Key("cloudflare.ray_id")
+	attrAttempts    = attribute.Key("cloudflare.attempts")
+	attrStatusCode  = attribute.Key("http.response.status_code")
+	attrErrorType   = attribute.Key("error.type")
+	attrR2Direction = attribute.Key("cloudflare.r2.direction")
+)
+
+// Services and R2 transfer directions used as attribute values
+const (
+	serviceKV        = "kv"
+	serviceR2        = "r2"
+	serviceD1        = "d1"
+	directionSent    = "sent"
+	directionReceive = "received"
+)
+
+// telemetry holds the tracer and instruments of one client
+type telemetry struct {
+	service string
+	tracer  trace.Tracer
+
+	duration    metric.Float64Histogram
+	errors      metric.Int64Counter
+	retries     metric.Int64Counter
+	rowsRead    metric.Int64Counter
+	rowsWritten metric.Int64Counter
+	bytes       metric.Int64Counter
+}
+
+// newTelemetry creates the instruments for service from the config's
+// providers, falling back to no-op providers when they are not set
+func newTelemetry(config *CloudflareConfig, service string) *telemetry {
+	var tp trace.TracerProvider = tracenoop.NewTracerProvider()
+	if config.TracerProvider != nil {
+		tp = config.TracerProvider
+	}
+	var mp metric.MeterProvider = metricnoop.NewMeterProvider()
+	if config.MeterProvider != nil {
+		mp = config.MeterProvider
+	}
+
+	meter := mp.Meter(instrumentationName)
+	noop := metricnoop.Meter{}
+
+	t := &telemetry{
+		service: service,
+		tracer:  tp.Tracer(instrumentationName),
+	}
+
+	var err error
+	if t.duration, err = meter.Float64Histogram("cloudflare.client.operation.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of Cloudflare client operations, including retries")); err != nil {
+		t.duration, _ = noop.Float64Histogram("")
+	}
+	if t.errors, err = meter.Int64Counter("cloudflare.client.operation.errors",
+		metric.WithDescription("Number of failed Cloudflare client operations")); err != nil {
+		t.errors, _ = noop.Int64Counter("")
+	}
+	if t.retries, err = meter.Int64Counter("cloudflare.client.retries",
+		metric.WithDescription("Number of retried Cloudflare API requests")); err != nil {
+		t.retries, _ = noop.Int64Counter("")
+	}
+	if t.rowsRead, err = meter.Int64Counter("cloudflare.d1.rows_read",
+		metric.WithDescription("Rows read by D1 queries, as billed")); err != nil {
+		t.rowsRead, _ = noop.Int64Counter("")
+	}
+	if t.rowsWritten, err = meter.Int64Counter("cloudflare.d1.rows_written",
+		metric.WithDescription("Rows written by D1 queries, as billed")); err != nil {
+		t.rowsWritten, _ = noop.Int64Counter("")
+	}
+	if t.bytes, err = meter.Int64Counter("cloudflare.r2.bytes",
+		metric.WithUnit("By"), metric.WithDescription("Object bytes transferred to and from R2")); err != nil {
+		t.bytes, _ = noop.Int64Counter("")
+	}
+
+	return t
+}
+
+type operationKey struct{}
+
+// operation tracks a single client method call
+type operation struct {
+	tel      *telemetry
+	ctx      context.Context
+	span     trace.Span
+	start    time.Time
+	attrs    []attribute.KeyValue
+	attempts int
+	retries  int
+
+	// transfer marks R2 object uploads and downloads, whose bodies are counted
+	transfer bool
+}
+
+// start begins a span for a client method. The returned context carries the
+// operation so the transport can record every attempt on it.
+func (t *telemetry) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	attrs = append([]attribute.KeyValue{attrService.String(t.service), attrOperation.String(name)}, attrs...)
+
+	ctx, span := t.tracer.Start(ctx, t.service+"."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	op := &operation{tel: t, span: span, start: time.Now(), attrs: attrs}
+	op.ctx = context.WithValue(ctx, operationKey{}, op)
+
+	return op.ctx, op
+}
+
+// attempt records the outcome of one HTTP attempt. An operation may send
+// several requests, for example to page or poll, so attempts are not retries.
+func (op *operation) attempt(resp *http.Response, err error) {
+	op.attempts++
+	if resp == nil {
+		return
+	}
+
+	op.span.SetAttributes(attrStatusCode.Int(resp.StatusCode))
+	if ray := resp.Header.Get("Cf-Ray"); ray != "" {
+		op.span.SetAttributes(attrRayID.String(ray))
+	}
+}
+
+// retry records that a request is about to be sent again as the given attempt
+func (op *operation) retry(attempt int) {
+	op.retries++
+	op.span.AddEvent("retry", trace.WithAttributes(attrAttempts.Int(attempt)))
+}
+
+// end finishes the span and records the duration, retries and any error
+func (op *operation) end(err error) {
+	set := metric.WithAttributes(op.attrs...)
+
+	if op.attempts > 0 {
+		op.span.SetAttributes(attrAttempts.Int(op.attempts))
+	}
+	if op.retries > 0 {
+		op.tel.retries.Add(op.ctx, int64(op.retries), set)
+	}
+
+	if err != nil {
+		errorType := errorType(err)
+		op.span.RecordError(err)
+		op.span.SetStatus(codes.Error, err.Error())
+		op.tel.errors.Add(op.ctx, 1, op.with(attrErrorType.String(errorType)))
+	}
+
+	op.tel.duration.Record(op.ctx, time.Since(op.start).Seconds(), set)
+	op.span.End()
+}
+
+// recordD1 adds the rows read and written by D1 statements to the operation
+func (op *operation) recordD1(metas ...D1Meta) {
+	var read, written int
+	for _, meta := range metas {
+		read += meta.RowsRead
+		written += meta.RowsWritten
+	}
+
+	op.span.SetAttributes(
+		attribute.Int("cloudflare.d1.rows_read", read),
+		attribute.Int("cloudflare.d1.rows_written", written),
+	)
+	set := metric.WithAttributes(op.attrs...)
+	op.tel.rowsRead.Add(op.ctx, int64(read), set)
+	op.tel.rowsWritten.Add(op.ctx, int64(written), set)
+}
+
+// recordBytes adds object bytes transferred in the given direction
+func (op *operation) recordBytes(direction string, n int64) {
+	if n <= 0 {
+		return
+	}
+	op.tel.bytes.Add(op.ctx, n, op.with(attrR2Direction.String(direction)))
+}
+
+// with returns the operation's attributes plus extra as a metric option
+func (op *operation) with(extra ...attribute.KeyValue) metric.MeasurementOption {
+	attrs := make([]attribute.KeyValue, 0, len(op.attrs)+len(extra))
+	attrs = append(append(attrs, op.attrs...), extra...)
+	return metric.WithAttributes(attrs...)
+}
+
+// errorType classifies err for the error.type attribute
+func errorType(err error) string {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return strconv.Itoa(apiErr.StatusCode)
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	return "other"
+}
+
+// telemetryTransport records every attempt on the operation in the request
+// context. It sits inside the retry transport so that it sees each attempt.
+type telemetryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *telemetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	op, ok := req.Context().Value(operationKey{}).(*operation)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	if op.transfer && req.Body != nil && req.Body != http.NoBody {
+		body := &countingReader{ReadCloser: req.Body}
+		body.done = func(n int64) { op.recordBytes(directionSent, n) }
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	op.attempt(resp, err)
+
+	if op.transfer && resp != nil && req.Method == http.MethodGet && resp.StatusCode == http.StatusOK {
+		body := &countingReader{ReadCloser: resp.Body}
+		body.done = func(n int64) { op.recordBytes(directionReceive, n) }
+		resp.Body = body
+	}
+
+	return resp, err
+}
+
+// countingReader counts the bytes read through it and reports them once when closed
+type countingReader struct {
+	io.ReadCloser
+	n    int64
+	done func(int64)
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) Close() error {
+	if r.done != nil {
+		r.done(r.n)
+		r.done = nil
+	}
+	return r.ReadCloser.Close()
+}
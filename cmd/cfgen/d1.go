@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+
+// runD1 implements the d1 subcommand
+func runD1(args []string) error {
+	flags := flag.NewFlagSet("d1", flag.ExitOnError)
+	databaseID := flags.String("database", os.Getenv("CLOUDFLARE_D1_DATABASE_ID"), "D1 database ID")
+	pkg := flags.String("package", "models", "package name of the generated file")
+	out := flags.String("out", "", "output file (default stdout)")
+	tables := flags.String("tables", "", "comma separated tables to generate (default all)")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout for reading the schema")
+	flags.Parse(args)
+
+	if *databaseID == "" {
+		return fmt.Errorf("-database is required")
+	}
+
+	storage := cloudflare.NewStorageFromEnv()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	schema, err := storage.D1.Schema(ctx, *databaseID)
+	if err != nil {
+		return err
+	}
+
+	if *tables != "" {
+		schema, err = filterTables(schema, strings.Split(*tables, ","))
+		if err != nil {
+			return err
+		}
+	}
+
+	src, err := generateD1(schema, *pkg)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(*out, src, 0o644)
+}
+
+// filterTables keeps only the named tables
+func filterTables(schema *cloudflare.D1Schema, names []string) (*cloudflare.D1Schema, error) {
+	filtered := &cloudflare.D1Schema{}
+	for _, name := range names {
+		table, ok := schema.Table(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("table not found: %s", name)
+		}
+		filtered.Tables = append(filtered.Tables, *table)
+	}
+	return filtered, nil
+}
+
+// d1Model is the template data for a single table
+type d1Model struct {
+	Table      string
+	Type       string
+	Plural     string
+	Columns    []d1Field
+	Keys       []d1Field
+	NonKeys    []d1Field
+	Insertable []d1Field
+
+	// RowID is the INTEGER PRIMARY KEY column that aliases the rowid, if any
+	RowID *d1Field
+}
+
+// d1Field is the template data for a single column
+type d1Field struct {
+	Column string
+	Name   string
+	Type   string
+}
+
+// generateD1 renders and formats the Go source for every table in schema
+func generateD1(schema *cloudflare.D1Schema, pkg string) ([]byte, error) {
+	data := struct {
+		Package   string
+		NeedsTime bool
+		Models    []d1Model
+	}{Package: pkg}
+
+	typeTables := make(map[string]string)
+	for _, table := range schema.Tables {
+		model := d1Model{
+			Table:  table.Name,
+			Type:   singular(goName(table.Name)),
+			Plural: goName(table.Name),
+		}
+		if model.Type == model.Plural {
+			model.Plural += "List"
+		}
+		if other, ok := typeTables[model.Type]; ok {
+			return nil, fmt.Errorf("tables %s and %s both map to type %s", other, table.Name, model.Type)
+		}
+		typeTables[model.Type] = table.Name
+
+		names := make(map[string]string)
+		pk := table.PrimaryKey()
+		for _, column := range table.Columns {
+			field := d1Field{
+				Column: column.Name,
+				Name:   goName(column.Name),
+				Type:   goType(column),
+			}
+			if other, ok := names[field.Name]; ok {
+				return nil, fmt.Errorf("columns %s and %s of table %s both map to field %s",
+					other, column.Name, table.Name, field.Name)
+			}
+			if generatedMethods[field.Name] {
+				return nil, fmt.Errorf("column %s of table %s maps to field %s, which is a generated method",
+					column.Name, table.Name, field.Name)
+			}
+			names[field.Name] = column.Name
+			if strings.HasPrefix(field.Type, "time.") || strings.HasPrefix(field.Type, "*time.") {
+				data.NeedsTime = true
+			}
+
+			model.Columns = append(model.Columns, field)
+			if column.PrimaryKey == 0 {
+				model.NonKeys = append(model.NonKeys, field)
+			}
+
+			if len(pk) == 1 && column.PrimaryKey == 1 && strings.EqualFold(column.Type, "INTEGER") {
+				f := field
+				model.RowID = &f
+				continue
+			}
+			model.Insertable = append(model.Insertable, field)
+		}
+
+		for _, column := range pk {
+			for _, field := range model.Columns {
+				if field.Column == column.Name {
+					model.Keys = append(model.Keys, field)
+				}
+			}
+		}
+
+		data.Models = append(data.Models, model)
+	}
+
+	var buf bytes.Buffer
+	if err := d1Template.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error rendering template: %w", err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated code: %w\n%s", err, buf.Bytes())
+	}
+
+	return src, nil
+}
+
+// generatedMethods are the methods generated on every model type, which
+// no field may be named after
+var generatedMethods = map[string]bool{
+	"InsertParams": true, "KeyParams": true, "UpdateParams": true,
+}
+
+// goType maps an SQLite column declaration to a Go type using SQLite's
+// type affinity rules. Nullable columns become pointers.
+func goType(column cloudflare.D1Column) string {
+	declared := strings.ToUpper(column.Type)
+
+	var t string
+	switch {
+	case strings.Contains(declared, "INT"):
+		t = "int64"
+	case strings.Contains(declared, "BOOL"):
+		t = "bool"
+	case strings.Contains(declared, "DATE"), strings.Contains(declared, "TIME"):
+		t = "time.Time"
+	case strings.Contains(declared, "CHAR"), strings.Contains(declared, "CLOB"), strings.Contains(declared, "TEXT"):
+		t = "string"
+	case declared == "", strings.Contains(declared, "BLOB"):
+		return "[]byte"
+	case strings.Contains(declared, "REAL"), strings.Contains(declared, "FLOA"), strings.Contains(declared, "DOUB"):
+		t = "float64"
+	default:
+		t = "float64"
+	}
+
+	if !column.NotNull && column.PrimaryKey == 0 {
+		return "*" + t
+	}
+	return t
+}
+
+// commonInitialisms are written in upper case in Go identifiers
+var commonInitialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "TTL": true, "UID": true,
+	"URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// goName converts an SQL identifier such as "user_id" into a Go name such as "UserID"
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(word)
+		sb.WriteRune(unicode.ToUpper(r))
+		sb.WriteString(word[size:])
+	}
+
+	// Names must start with an upper case letter to be exported
+	s := sb.String()
+	if r, _ := utf8.DecodeRuneInString(s); !unicode.IsUpper(r) {
+		s = "X" + s
+	}
+	return s
+}
+
+// singular makes a best effort to turn a plural table name into a type name
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"), strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"), strings.HasSuffix(name, "is"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// reservedParams are identifiers used by the generated functions that a
+// parameter named after a column must not shadow
+var reservedParams = map[string]bool{
+	"ctx": true, "client": true, "databaseID": true, "r": true, "result": true, "err": true,
+	"context": true, "fmt": true, "time": true, "cloudflare": true,
+}
+
+// param returns the name of the function parameter for a key column
+func param(f d1Field) string {
+	name := lowerFirst(f.Name)
+	if token.IsKeyword(name) || reservedParams[name] || types.Universe.Lookup(name) != nil {
+		name += "_"
+	}
+	return name
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func lowerFirst(s string) string {
+	for i, r := range s {
+		if !unicode.IsUpper(r) {
+			if i == 0 {
+				return s
+			}
+			// Keep the last capital of a leading initialism: "IDValue" -> "idValue"
+			if _, size := utf8.DecodeLastRuneInString(s[:i]); i > size {
+				i -= size
+			}
+			return strings.ToLower(s[:i]) + s[i:]
+		}
+	}
+	return strings.ToLower(s)
+}
+
+var d1Template = template.Must(template.New("d1").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+	"columns": func(fields []d1Field) string {
+		quoted := make([]string, len(fields))
+		for i, f := range fields {
+			quoted[i] = quoteIdent(f.Column)
+		}
+		return strings.Join(quoted, ", ")
+	},
+	"placeholders": func(fields []d1Field) string {
+		return strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ")
+	},
+	"assignments": func(fields []d1Field) string {
+		sets := make([]string, len(fields))
+		for i, f := range fields {
+			sets[i] = quoteIdent(f.Column) + " = ?"
+		}
+		return strings.Join(sets, ", ")
+	},
+	"keyWhere": func(fields []d1Field) string {
+		conds := make([]string, len(fields))
+		for i, f := range fields {
+			conds[i] = quoteIdent(f.Column) + " = ?"
+		}
+		return strings.Join(conds, " AND ")
+	},
+	"ident":      quoteIdent,
+	"lowerFirst": lowerFirst,
+	"param":      param,
+}).Parse(`// Code generated by cfgen d1; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+{{- if .NeedsTime}}
+	"time"
+{{- end}}
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+{{range $m := .Models}}
+// {{$m.Type}} is a row of the {{$m.Table}} table
+type {{$m.Type}} struct {
+{{- range $m.Columns}}
+	{{.Name}} {{.Type}} ` + "`" + `d1:{{quote .Column}}` + "`" + `
+{{- end}}
+}
+
+// {{$m.Type}}Table is the name of the {{$m.Table}} table
+const {{$m.Type}}Table = {{quote $m.Table}}
+
+// {{$m.Type}}Columns is the column list of the {{$m.Table}} table for SELECT statements
+const {{$m.Type}}Columns = {{quote (columns $m.Columns)}}
+
+// InsertParams returns the values bound by Insert{{$m.Type}}
+func (r *{{$m.Type}}) InsertParams() []interface{} {
+	return []interface{}{ {{- range $i, $f := $m.Insertable}}{{if $i}}, {{end}}r.{{$f.Name}}{{end -}} }
+}
+
+// Insert{{$m.Type}} inserts a row into the {{$m.Table}} table
+func Insert{{$m.Type}}(ctx context.Context, client *cloudflare.D1Client, databaseID string, r *{{$m.Type}}) error {
+	{{if $m.RowID}}result{{else}}_{{end}}, err := client.ExecuteQuery(ctx, databaseID,
+		{{quote (printf "INSERT INTO %s (%s) VALUES (%s)" (ident $m.Table) (columns $m.Insertable) (placeholders $m.Insertable))}},
+		r.InsertParams())
+	if err != nil {
+		return fmt.Errorf("error inserting {{$m.Table}}: %w", err)
+	}
+{{- if $m.RowID}}
+
+	r.{{$m.RowID.Name}} = int64(result.Meta.LastRowID)
+{{- end}}
+
+	return nil
+}
+
+// List{{$m.Plural}} returns the rows of the {{$m.Table}} table matching an optional
+// WHERE condition, for example List{{$m.Plural}}(ctx, client, databaseID, "name = ?", name)
+func List{{$m.Plural}}(ctx context.Context, client *cloudflare.D1Client, databaseID, where string, params ...interface{}) ([]{{$m.Type}}, error) {
+	query := "SELECT " + {{$m.Type}}Columns + {{quote (printf " FROM %s" (ident $m.Table))}}
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	return cloudflare.QueryInto[{{$m.Type}}](ctx, client, databaseID, query, params)
+}
+{{- if $m.Keys}}
+
+// KeyParams returns the primary key values of the row
+func (r *{{$m.Type}}) KeyParams() []interface{} {
+	return []interface{}{ {{- range $i, $f := $m.Keys}}{{if $i}}, {{end}}r.{{$f.Name}}{{end -}} }
+}
+
+// Get{{$m.Type}} returns the row of the {{$m.Table}} table with the given primary key.
+// It returns cloudflare.ErrNoRows if there is no such row.
+func Get{{$m.Type}}(ctx context.Context, client *cloudflare.D1Client, databaseID string{{range $m.Keys}}, {{param .}} {{.Type}}{{end}}) (*{{$m.Type}}, error) {
+	return cloudflare.QueryOne[{{$m.Type}}](ctx, client, databaseID,
+		"SELECT " + {{$m.Type}}Columns + {{quote (printf " FROM %s WHERE %s" (ident $m.Table) (keyWhere $m.Keys))}},
+		[]interface{}{ {{- range $i, $f := $m.Keys}}{{if $i}}, {{end}}{{param $f}}{{end -}} })
+}
+{{- if $m.NonKeys}}
+
+// UpdateParams returns the values bound by Update{{$m.Type}}: every non-key
+// column followed by the primary key
+func (r *{{$m.Type}}) UpdateParams() []interface{} {
+	return append([]interface{}{ {{- range $i, $f := $m.NonKeys}}{{if $i}}, {{end}}r.{{$f.Name}}{{end -}} }, r.KeyParams()...)
+}
+
+// Update{{$m.Type}} overwrites the row of the {{$m.Table}} table with the primary key of r.
+// It returns cloudflare.ErrNoRows if there is no such row.
+func Update{{$m.Type}}(ctx context.Context, client *cloudflare.D1Client, databaseID string, r *{{$m.Type}}) error {
+	result, err := client.ExecuteQuery(ctx, databaseID,
+		{{quote (printf "UPDATE %s SET %s WHERE %s" (ident $m.Table) (assignments $m.NonKeys) (keyWhere $m.Keys))}},
+		r.UpdateParams())
+	if err != nil {
+		return fmt.Errorf("error updating {{$m.Table}}: %w", err)
+	}
+
+	if result.Meta.Changes == 0 {
+		return cloudflare.ErrNoRows
+	}
+
+	return nil
+}
+{{- end}}
+
+// Delete{{$m.Type}} deletes the row of the {{$m.Table}} table with the given primary key.
+// It returns cloudflare.ErrNoRows if there is no such row.
+func Delete{{$m.Type}}(ctx context.Context, client *cloudflare.D1Client, databaseID string{{range $m.Keys}}, {{param .}} {{.Type}}{{end}}) error {
+	result, err := client.ExecuteQuery(ctx, databaseID,
+		{{quote (printf "DELETE FROM %s WHERE %s" (ident $m.Table) (keyWhere $m.Keys))}},
+		[]interface{}{ {{- range $i, $f := $m.Keys}}{{if $i}}, {{end}}{{param $f}}{{end -}} })
+	if err != nil {
+		return fmt.Errorf("error deleting {{$m.Table}}: %w", err)
+	}
+
+	if result.Meta.Changes == 0 {
+		return cloudflare.ErrNoRows
+	}
+
+	return nil
+}
+{{- end}}
+{{end}}`))
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/BLANK-13/go-cloud-utils/cloudflare"
+)
+
+func TestGenerateD1Compiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+
+	schema := &cloudflare.D1Schema{Tables: []cloudflare.D1Table{
+		{
+			Name: "events",
+			Columns: []cloudflare.D1Column{
+				{Name: "for", Type: "TEXT", NotNull: true, PrimaryKey: 1},
+				{Name: "case", Type: "INTEGER", NotNull: true, PrimaryKey: 2},
+				{Name: "client", Type: "TEXT", NotNull: true, PrimaryKey: 3},
+				{Name: "ctx", Type: "TEXT", NotNull: true, PrimaryKey: 4},
+				{Name: "cloudflare", Type: "TEXT", NotNull: true, PrimaryKey: 5},
+				{Name: "nil", Type: "TEXT", NotNull: true, PrimaryKey: 6},
+				{Name: "var", Type: "TEXT"},
+				{Name: "created_at", Type: "DATETIME"},
+				{Name: "été", Type: "TEXT"},
+				{Name: "名前", Type: "TEXT"},
+			},
+		},
+	}}
+
+	src, err := generateD1(schema, "models")
+	if err != nil {
+		t.Fatalf("generateD1: %v", err)
+	}
+
+	// The generated package imports this module, so build it in a workspace with it
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"models.go": string(src),
+		"go.mod":    "module generated\n\ngo 1.23.1\n",
+		"go.work":   "go 1.23.1\n\nuse (\n\t.\n\t" + strconv.Quote(root) + "\n)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK="+filepath.Join(dir, "go.work"), "GOFLAGS=-mod=readonly")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s\n%s", err, out, src)
+	}
+}
+
+func TestGenerateD1NameCollision(t *testing.T) {
+	schema := &cloudflare.D1Schema{Tables: []cloudflare.D1Table{
+		{
+			Name: "users",
+			Columns: []cloudflare.D1Column{
+				{Name: "user_id", Type: "INTEGER", PrimaryKey: 1},
+				{Name: "UserID", Type: "TEXT"},
+			},
+		},
+	}}
+
+	_, err := generateD1(schema, "models")
+	if err == nil || !strings.Contains(err.Error(), "UserID") {
+		t.Fatalf("expected a field collision error, got %v", err)
+	}
+
+	schema.Tables[0].Columns[1].Name = "key_params"
+	_, err = generateD1(schema, "models")
+	if err == nil || !strings.Contains(err.Error(), "KeyParams") {
+		t.Fatalf("expected a method collision error, got %v", err)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":   "UserID",
+		"createdAt": "CreatedAt",
+		"2fa":       "X2fa",
+		"été":       "Été",
+		"名前":        "X名前",
+		"":          "X",
+	}
+	for name, want := range tests {
+		if got := goName(name); got != want {
+			t.Errorf("goName(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestParam(t *testing.T) {
+	tests := map[string]string{
+		"ID":         "id",
+		"Name":       "name",
+		"For":        "for_",
+		"Interface":  "interface_",
+		"Go":         "go_",
+		"DatabaseID": "databaseID_",
+		"String":     "string_",
+		"Result":     "result_",
+	}
+	for name, want := range tests {
+		if got := param(d1Field{Name: name}); got != want {
+			t.Errorf("param(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
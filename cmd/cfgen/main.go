@@ -0,0 +1,50 @@
+// Command cfgen generates Go code from Cloudflare resources.
+//
+// Usage:
+//
+//	cfgen d1 -database <database-id> [-package models] [-out models/d1.go] [-tables users,posts]
+//
+// The d1 subcommand reads the schema of a live D1 database and writes model
+// structs, typed CRUD functions and parameter binding helpers for each table.
+// Credentials are read from the CLOUDFLARE_API_TOKEN and CLOUDFLARE_ACCOUNT_ID
+// environment variables.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "d1":
+		err = runD1(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "cfgen: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cfgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: cfgen <command> [flags]
+
+Commands:
+  d1    generate Go models and CRUD functions from a D1 database schema
+
+Run "cfgen <command> -h" for the flags of a command.`)
+}
@@ -269,9 +269,10 @@ func cloudflareExample(ctx context.Context) {
 	if namespaceID != "" {
 		log.Println("Writing to KV...")
 		value := []byte("Hello, world!")
-		expiration := int64(3600) // 1 hour in seconds
 
-		err := storage.KV.WriteValue(ctx, namespaceID, "test-key", value, &expiration)
+		err := storage.KV.WriteValue(ctx, namespaceID, "test-key", value, &cloudflare.WriteOptions{
+			TTL: time.Hour,
+		})
 		if err != nil {
 			log.Printf("KV write failed: %v", err)
 		} else {
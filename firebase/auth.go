@@ -2,8 +2,11 @@ package firebase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -24,6 +27,10 @@ const (
 type FirebaseAuth struct {
 	App  *firebase.App
 	Auth *auth.Client
+
+	// Logger receives token verification failures and rejected requests; nil
+	// disables logging. ID tokens are logged only as a short fingerprint.
+	Logger *slog.Logger
 }
 
 // InitFirebase initializes Firebase with the provided service account key file
@@ -87,9 +94,20 @@ func (fa *FirebaseAuth) VerifyIDToken(ctx context.Context, idToken string) (*aut
 
 	token, err := fa.Auth.VerifyIDToken(ctx, idToken)
 	if err != nil {
+		if fa.Logger != nil {
+			fa.Logger.WarnContext(ctx, "firebase ID token verification failed",
+				slog.String("token", tokenFingerprint(idToken)),
+				slog.String("error", err.Error()))
+		}
 		return nil, fmt.Errorf("error verifying ID token: %v", err)
 	}
 
+	if fa.Logger != nil {
+		fa.Logger.DebugContext(ctx, "firebase ID token verified",
+			slog.String("uid", token.UID),
+			slog.String("token", tokenFingerprint(idToken)))
+	}
+
 	return token, nil
 }
 
@@ -203,6 +221,7 @@ func (fa *FirebaseAuth) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idToken, err := GetTokenFromRequest(r)
 		if err != nil {
+			fa.logRejected(r, err)
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
@@ -228,6 +247,7 @@ func RequireAuth(fa *FirebaseAuth, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idToken, err := GetTokenFromRequest(r)
 		if err != nil {
+			fa.logRejected(r, err)
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
@@ -245,6 +265,23 @@ func RequireAuth(fa *FirebaseAuth, next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// logRejected logs a request rejected for a missing or malformed Authorization header
+func (fa *FirebaseAuth) logRejected(r *http.Request, err error) {
+	if fa.Logger == nil {
+		return
+	}
+	fa.Logger.DebugContext(r.Context(), "request rejected without valid authorization header",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("error", err.Error()))
+}
+
+// tokenFingerprint identifies a token in logs without revealing it
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
 // GetUIDFromContext extracts the user ID from the context
 func GetUIDFromContext(ctx context.Context) (string, error) {
 